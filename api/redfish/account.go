@@ -0,0 +1,587 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Predefined roles accounts are assigned.
+const (
+	RoleAdministrator = "Administrator"
+	RoleOperator      = "Operator"
+	RoleReadOnly      = "ReadOnly"
+)
+
+// Redfish privileges checked per operation.
+const (
+	PrivilegeLogin               = "Login"
+	PrivilegeConfigureComponents = "ConfigureComponents"
+	PrivilegeConfigureManager    = "ConfigureManager"
+	PrivilegeConfigureUsers      = "ConfigureUsers"
+	PrivilegeConfigureSelf       = "ConfigureSelf"
+)
+
+// roleContextKey holds the role of the authenticated user in the gin context.
+const roleContextKey = "redfish.role"
+
+const minPasswordLength = 8
+
+var rolePrivileges = map[string][]string{
+	RoleAdministrator: {
+		PrivilegeLogin,
+		PrivilegeConfigureComponents,
+		PrivilegeConfigureManager,
+		PrivilegeConfigureUsers,
+		PrivilegeConfigureSelf,
+	},
+	RoleOperator: {
+		PrivilegeLogin,
+		PrivilegeConfigureComponents,
+		PrivilegeConfigureSelf,
+	},
+	RoleReadOnly: {
+		PrivilegeLogin,
+		PrivilegeConfigureSelf,
+	},
+}
+
+var roleIds = []string{RoleAdministrator, RoleOperator, RoleReadOnly}
+
+func hasPrivilege(role, privilege string) bool {
+	return slices.Contains(rolePrivileges[role], privilege)
+}
+
+// requiredPrivilege returns the privilege needed to perform a request on a
+// route. Reads only need Login, changes to systems need ConfigureComponents
+// and changes to the service itself, such as firmware updates, need
+// ConfigureManager. Accounts are read with ConfigureSelf, other users'
+// accounts with ConfigureUsers.
+func requiredPrivilege(method, path string) string {
+	if method == "GET" || method == "HEAD" {
+		if strings.HasPrefix(path, "/redfish/v1/AccountService/Accounts") {
+			// Reading other users' accounts is checked by the handlers.
+			return PrivilegeConfigureSelf
+		}
+		return PrivilegeLogin
+	}
+
+	switch {
+	case path == "/redfish/v1/AccountService/Accounts/:AccountId" && method == "PATCH":
+		// Users may change their own password, checked by the handler.
+		return PrivilegeConfigureSelf
+	case strings.HasPrefix(path, "/redfish/v1/AccountService"):
+		return PrivilegeConfigureUsers
+	case strings.HasPrefix(path, "/redfish/v1/EventService/Subscriptions"):
+		return PrivilegeConfigureComponents
+	case strings.HasPrefix(path, "/redfish/v1/SessionService/Sessions"):
+		// Users may log out of their own sessions, checked by the handler.
+		return PrivilegeLogin
+	case strings.HasPrefix(path, "/redfish/v1/Systems/:ComputerSystemId/SecureBoot"):
+		// Key enrollment decides what the system will execute.
+		return PrivilegeConfigureManager
+	case strings.HasPrefix(path, "/redfish/v1/Systems/:ComputerSystemId/LogServices"):
+		// Clearing a log erases the history of every operator.
+		return PrivilegeConfigureManager
+	case strings.HasPrefix(path, "/redfish/v1/Systems"):
+		return PrivilegeConfigureComponents
+	}
+
+	return PrivilegeConfigureManager
+}
+
+// account is a local user. Passwords are stored as bcrypt hashes.
+type account struct {
+	PasswordHash string `json:"password_hash"`
+	Role         string `json:"role"`
+	Enabled      bool   `json:"enabled"`
+}
+
+// accountStore persists accounts keyed by user name to a JSON file. An empty
+// path keeps the accounts in memory only.
+type accountStore struct {
+	mu   sync.Mutex
+	path string
+
+	Accounts map[string]account `json:"accounts"`
+}
+
+func loadAccounts(path string) (*accountStore, error) {
+	s := &accountStore{
+		path:     path,
+		Accounts: make(map[string]account),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+
+	if s.Accounts == nil {
+		s.Accounts = make(map[string]account)
+	}
+
+	return s, nil
+}
+
+// save writes the accounts to a temporary file and renames it over the
+// previous one. The caller must hold mu.
+func (s *accountStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+func (s *accountStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.Accounts)
+}
+
+func (s *accountStore) get(userName string) (account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.Accounts[userName]
+	return a, ok
+}
+
+func (s *accountStore) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.Accounts))
+	for name := range s.Accounts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// update applies fn to the account of userName and persists the result.
+// create must be set for accounts that do not exist yet.
+func (s *accountStore) update(userName string, create bool, fn func(*account) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.Accounts[userName]
+	if ok == create {
+		if create {
+			return fmt.Errorf("account %s already exists", userName)
+		}
+		return fmt.Errorf("account %s not found", userName)
+	}
+
+	if err := fn(&a); err != nil {
+		return err
+	}
+
+	s.Accounts[userName] = a
+
+	return s.save()
+}
+
+func (s *accountStore) delete(userName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.Accounts[userName]; !ok {
+		return fmt.Errorf("account %s not found", userName)
+	}
+
+	delete(s.Accounts, userName)
+
+	return s.save()
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// userRole returns the role of an enabled user. The user configured in the
+// config file is always an Administrator.
+func (r *RedfishServer) userRole(userName string) (string, bool) {
+	if r.Config.AuthUsername != "" && userName == r.Config.AuthUsername {
+		return RoleAdministrator, true
+	}
+
+	a, ok := r.accounts.get(userName)
+	if !ok || !a.Enabled {
+		return "", false
+	}
+	return a.Role, true
+}
+
+// authorize aborts the request unless role holds the privilege needed for
+// the route.
+func (r *RedfishServer) authorize(c *gin.Context, role string) bool {
+	privilege := requiredPrivilege(c.Request.Method, c.FullPath())
+	if hasPrivilege(role, privilege) {
+		return true
+	}
+
+	c.AbortWithStatusJSON(403, redfishErrorCode(baseRegistry+"InsufficientPrivilege",
+		fmt.Errorf("role %s lacks the %s privilege", role, privilege)))
+	return false
+}
+
+// contextUser returns the authenticated user and role of a request. Both are
+// empty when authentication is disabled.
+func contextUser(c *gin.Context) (userName, role string) {
+	return c.GetString(userContextKey), c.GetString(roleContextKey)
+}
+
+// accountResource is the Redfish ManagerAccount resource.
+type accountResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Enabled  *bool         `json:"Enabled,omitempty"`
+	Id       *string       `json:"Id,omitempty"`
+	Links    *accountLinks `json:"Links,omitempty"`
+	Name     *string       `json:"Name,omitempty"`
+	Password *string       `json:"Password,omitempty"`
+	RoleId   *string       `json:"RoleId,omitempty"`
+	UserName *string       `json:"UserName,omitempty"`
+}
+
+type accountLinks struct {
+	Role *IdRef `json:"Role,omitempty"`
+}
+
+// roleResource is the Redfish Role resource.
+type roleResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	AssignedPrivileges *[]string `json:"AssignedPrivileges,omitempty"`
+	Id                 *string   `json:"Id,omitempty"`
+	IsPredefined       *bool     `json:"IsPredefined,omitempty"`
+	Name               *string   `json:"Name,omitempty"`
+	RoleId             *string   `json:"RoleId,omitempty"`
+}
+
+// accountService is the Redfish AccountService resource.
+type accountService struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Accounts          *IdRef  `json:"Accounts,omitempty"`
+	Id                *string `json:"Id,omitempty"`
+	MinPasswordLength *int    `json:"MinPasswordLength,omitempty"`
+	Name              *string `json:"Name,omitempty"`
+	Roles             *IdRef  `json:"Roles,omitempty"`
+	ServiceEnabled    *bool   `json:"ServiceEnabled,omitempty"`
+	Status            *Status `json:"Status,omitempty"`
+}
+
+func accountResourceFor(userName string, a account) accountResource {
+	return accountResource{
+		OdataId:   ptr("/redfish/v1/AccountService/Accounts/" + userName),
+		OdataType: ptr("#ManagerAccount.v1_7_0.ManagerAccount"),
+		Id:        ptr(userName),
+		Name:      ptr("User Account"),
+		UserName:  ptr(userName),
+		RoleId:    ptr(a.Role),
+		Enabled:   ptr(a.Enabled),
+		Links: &accountLinks{
+			Role: &IdRef{OdataId: ptr("/redfish/v1/AccountService/Roles/" + a.Role)},
+		},
+	}
+}
+
+// GetAccountService reports the password policy and links the accounts and
+// roles.
+func (r *RedfishServer) GetAccountService(c *gin.Context) {
+	resp := accountService{
+		OdataId:           ptr("/redfish/v1/AccountService"),
+		OdataType:         ptr("#AccountService.v1_10_0.AccountService"),
+		Id:                ptr("AccountService"),
+		Name:              ptr("Account Service"),
+		ServiceEnabled:    ptr(r.authEnabled()),
+		MinPasswordLength: ptr(minPasswordLength),
+		Accounts:          &IdRef{OdataId: ptr("/redfish/v1/AccountService/Accounts")},
+		Roles:             &IdRef{OdataId: ptr("/redfish/v1/AccountService/Roles")},
+		Status: &Status{
+			State:  ptr(StateEnabled),
+			Health: ptr(HealthOK),
+		},
+	}
+
+	c.JSON(200, &resp)
+}
+
+// ListAccounts lists the local accounts, only their own to users without
+// ConfigureUsers.
+func (r *RedfishServer) ListAccounts(c *gin.Context) {
+	ids := []IdRef{}
+	for _, name := range r.accounts.names() {
+		if r.canReadAccount(c, name) {
+			ids = append(ids, IdRef{OdataId: ptr("/redfish/v1/AccountService/Accounts/" + name)})
+		}
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#ManagerAccountCollection.ManagerAccountCollection"),
+		OdataType:         "#ManagerAccountCollection.ManagerAccountCollection",
+		Name:              ptr("Accounts Collection"),
+		OdataId:           "/redfish/v1/AccountService/Accounts",
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// canReadAccount reports whether the user of a request may read the account
+// userName: their own, or any with ConfigureUsers.
+func (r *RedfishServer) canReadAccount(c *gin.Context, userName string) bool {
+	current, role := contextUser(c)
+	return !r.authEnabled() || hasPrivilege(role, PrivilegeConfigureUsers) || current == userName
+}
+
+// CreateAccount adds a local account. Accounts cannot be created while
+// authentication is disabled, as the unauthenticated request would make
+// anyone able to reach the API its first Administrator.
+func (r *RedfishServer) CreateAccount(c *gin.Context) {
+	if !r.authEnabled() {
+		c.JSON(403, redfishErrorCode(baseRegistry+"InsufficientPrivilege", fmt.Errorf("accounts cannot be created while authentication is disabled, configure auth.username first")))
+		return
+	}
+
+	req := accountResource{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var missing []string
+	if req.UserName == nil || *req.UserName == "" {
+		missing = append(missing, "UserName")
+	}
+	if req.Password == nil {
+		missing = append(missing, "Password")
+	}
+	if req.RoleId == nil {
+		missing = append(missing, "RoleId")
+	}
+	if len(missing) > 0 {
+		propertyMissing(c, missing...)
+		return
+	}
+
+	userName := *req.UserName
+	if userName == r.Config.AuthUsername || strings.ContainsAny(userName, "/?#") {
+		invalidProperty(c, "UserName", "PropertyValueFormatError", userName, "UserName")
+		return
+	}
+
+	if !slices.Contains(roleIds, *req.RoleId) {
+		invalidProperty(c, "RoleId", "PropertyValueNotInList", *req.RoleId, "RoleId")
+		return
+	}
+
+	hash, err := hashPassword(*req.Password)
+	if err != nil {
+		// Passwords are not echoed in MessageArgs.
+		invalidProperty(c, "Password", "PropertyValueError", "Password")
+		return
+	}
+
+	err = r.accounts.update(userName, true, func(a *account) error {
+		a.PasswordHash = hash
+		a.Role = *req.RoleId
+		a.Enabled = req.Enabled == nil || *req.Enabled
+		return nil
+	})
+	if err != nil {
+		c.JSON(409, redfishErrorCode(baseRegistry+"ResourceAlreadyExists", err, "ManagerAccount", "UserName", userName))
+		return
+	}
+
+	a, _ := r.accounts.get(userName)
+	resp := accountResourceFor(userName, a)
+
+	c.Header("Location", *resp.OdataId)
+	c.JSON(201, &resp)
+}
+
+// GetAccount reports a local account. Users without ConfigureUsers may only
+// read their own.
+func (r *RedfishServer) GetAccount(c *gin.Context) {
+	userName := c.Param("AccountId")
+
+	if !r.canReadAccount(c, userName) {
+		c.JSON(403, redfishErrorCode(baseRegistry+"InsufficientPrivilege", fmt.Errorf("only your own account can be read")))
+		return
+	}
+
+	a, ok := r.accounts.get(userName)
+	if !ok {
+		resourceNotFound(c, "ManagerAccount", userName)
+		return
+	}
+
+	resp := accountResourceFor(userName, a)
+
+	c.JSON(200, &resp)
+}
+
+// SetAccount changes the password, role or state of a local account. Users
+// without ConfigureUsers may only change their own password.
+func (r *RedfishServer) SetAccount(c *gin.Context) {
+	userName := c.Param("AccountId")
+
+	req := accountResource{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	current, role := contextUser(c)
+	if r.authEnabled() && !hasPrivilege(role, PrivilegeConfigureUsers) {
+		if current != userName || req.RoleId != nil || req.Enabled != nil || req.UserName != nil {
+			c.JSON(403, redfishErrorCode(baseRegistry+"InsufficientPrivilege", fmt.Errorf("only the password of your own account can be changed")))
+			return
+		}
+	}
+
+	if req.UserName != nil && *req.UserName != userName {
+		invalidProperty(c, "UserName", "PropertyNotWritable", "UserName")
+		return
+	}
+
+	if req.RoleId != nil && !slices.Contains(roleIds, *req.RoleId) {
+		invalidProperty(c, "RoleId", "PropertyValueNotInList", *req.RoleId, "RoleId")
+		return
+	}
+
+	hash := ""
+	if req.Password != nil {
+		var err error
+		if hash, err = hashPassword(*req.Password); err != nil {
+			invalidProperty(c, "Password", "PropertyValueError", "Password")
+			return
+		}
+	}
+
+	if _, ok := r.accounts.get(userName); !ok {
+		resourceNotFound(c, "ManagerAccount", userName)
+		return
+	}
+
+	err := r.accounts.update(userName, false, func(a *account) error {
+		if hash != "" {
+			a.PasswordHash = hash
+		}
+		if req.RoleId != nil {
+			a.Role = *req.RoleId
+		}
+		if req.Enabled != nil {
+			a.Enabled = *req.Enabled
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	a, _ := r.accounts.get(userName)
+	resp := accountResourceFor(userName, a)
+
+	c.JSON(200, &resp)
+}
+
+// DeleteAccount removes a local account.
+func (r *RedfishServer) DeleteAccount(c *gin.Context) {
+	userName := c.Param("AccountId")
+
+	if _, ok := r.accounts.get(userName); !ok {
+		resourceNotFound(c, "ManagerAccount", userName)
+		return
+	}
+
+	if err := r.accounts.delete(userName); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
+
+// ListRoles lists the predefined roles.
+func (r *RedfishServer) ListRoles(c *gin.Context) {
+	ids := []IdRef{}
+	for _, role := range roleIds {
+		ids = append(ids, IdRef{OdataId: ptr("/redfish/v1/AccountService/Roles/" + role)})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#RoleCollection.RoleCollection"),
+		OdataType:         "#RoleCollection.RoleCollection",
+		Name:              ptr("Roles Collection"),
+		OdataId:           "/redfish/v1/AccountService/Roles",
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// GetRole reports the privileges assigned to a role.
+func (r *RedfishServer) GetRole(c *gin.Context) {
+	role := c.Param("RoleId")
+
+	privileges, ok := rolePrivileges[role]
+	if !ok {
+		resourceNotFound(c, "Role", role)
+		return
+	}
+
+	c.JSON(200, &roleResource{
+		OdataId:            ptr("/redfish/v1/AccountService/Roles/" + role),
+		OdataType:          ptr("#Role.v1_3_1.Role"),
+		Id:                 ptr(role),
+		Name:               ptr(role + " Role"),
+		RoleId:             ptr(role),
+		IsPredefined:       ptr(true),
+		AssignedPrivileges: &privileges,
+	})
+}
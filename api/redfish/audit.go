@@ -0,0 +1,90 @@
+package redfish
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const auditResultOk = "ok"
+
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	System string    `json:"system"`
+	Mac    string    `json:"mac,omitempty"`
+	User   string    `json:"user,omitempty"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail,omitempty"`
+	Result string    `json:"result"`
+}
+
+var auditMu sync.Mutex
+
+// audit records an action against a system on behalf of user, which is empty
+// for actions the service takes by itself. Entries are logged, added to the
+// event log of the system and, when an audit log is configured, appended to
+// it as JSON lines.
+func (r *RedfishServer) audit(user string, sys RedfishSystem, action, detail string, err error) {
+	entry := auditEntry{
+		Time:   time.Now().UTC(),
+		System: strconv.Itoa(sys.UnifiPort),
+		Mac:    sys.MacAddress,
+		User:   user,
+		Action: action,
+		Detail: detail,
+		Result: auditResultOk,
+	}
+	if err != nil {
+		entry.Result = err.Error()
+	}
+
+	log.Printf("audit: system %s %s %q by %q: %s", entry.System, entry.Action, entry.Detail, entry.User, entry.Result)
+
+	if err := r.logs.append(sys.UnifiPort, entry); err != nil {
+		log.Printf("audit: event log of system %s: %v", entry.System, err)
+	}
+
+	if r.Config.AuditLog == "" {
+		return
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit: encoding entry: %v", err)
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	f, err := os.OpenFile(r.Config.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("audit: opening %s: %v", r.Config.AuditLog, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Printf("audit: writing %s: %v", r.Config.AuditLog, err)
+	}
+}
+
+// logEvent records something that happened to a system in its event log
+// only, such as a file fetched over TFTP or a port changed on the controller.
+func (r *RedfishServer) logEvent(port int, mac, action, detail string) {
+	entry := auditEntry{
+		Time:   time.Now().UTC(),
+		System: strconv.Itoa(port),
+		Mac:    mac,
+		Action: action,
+		Detail: detail,
+		Result: auditResultOk,
+	}
+
+	if err := r.logs.append(port, entry); err != nil {
+		log.Printf("audit: event log of system %s: %v", entry.System, err)
+	}
+}
@@ -0,0 +1,292 @@
+package redfish
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+	"github.com/0x5a17ed/uefi/efi/efivario"
+	"github.com/appkins-org/go-redfish-uefi/pkg/firmware"
+	"github.com/gin-gonic/gin"
+)
+
+// configDxeGuid is the vendor GUID of the settings of the RPi UEFI firmware.
+var configDxeGuid = efiguid.MustFromString("cd7cc258-31db-22e6-9f22-63b0b8eed6b5")
+
+const configDxeAttributes = efivario.NonVolatile | efivario.BootServiceAccess | efivario.RuntimeAccess
+
+// Types of BIOS attributes.
+const (
+	biosBoolean     = "Boolean"
+	biosEnumeration = "Enumeration"
+	biosInteger     = "Integer"
+)
+
+// biosAttribute maps a Redfish BIOS attribute to the UINT32 EFI variable of
+// the firmware holding it. Enumeration values are stored as their index.
+type biosAttribute struct {
+	Name     string
+	Variable string
+	Type     string
+	Values   []string
+	Min, Max uint32
+	Default  uint32
+}
+
+// biosAttributes lists the settings of the RPi UEFI firmware exposed through
+// the Bios resource, with the defaults the firmware applies when a variable
+// has never been written.
+var biosAttributes = []biosAttribute{
+	{Name: "RamLimitTo3GB", Variable: "RamLimitTo3GB", Type: biosBoolean, Default: 1},
+	{Name: "SystemTableMode", Variable: "SystemTableMode", Type: biosEnumeration, Values: []string{"ACPI", "ACPI+DeviceTree", "DeviceTree"}, Default: 0},
+	{Name: "CpuClock", Variable: "CpuClock", Type: biosEnumeration, Values: []string{"Low", "Default", "Max", "Custom"}, Default: 1},
+	{Name: "CustomCpuClock", Variable: "CustomCpuClock", Type: biosInteger, Min: 100, Max: 2200, Default: 1500},
+	{Name: "FanOnGpio", Variable: "FanOnGpio", Type: biosInteger, Min: 0, Max: 27, Default: 0},
+	{Name: "FanTemp", Variable: "FanTemp", Type: biosInteger, Min: 30, Max: 90, Default: 60},
+}
+
+func findBiosAttribute(name string) (biosAttribute, bool) {
+	for _, a := range biosAttributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return biosAttribute{}, false
+}
+
+// value returns the Redfish representation of a raw variable value.
+func (a biosAttribute) value(raw uint32) any {
+	switch a.Type {
+	case biosBoolean:
+		return raw != 0
+	case biosEnumeration:
+		if int(raw) < len(a.Values) {
+			return a.Values[raw]
+		}
+		return fmt.Sprintf("%d", raw)
+	default:
+		return raw
+	}
+}
+
+// raw validates a Redfish attribute value and returns the variable value
+// storing it.
+func (a biosAttribute) raw(v any) (uint32, error) {
+	switch a.Type {
+	case biosBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return 0, propertyError("Attributes/"+a.Name, "PropertyValueTypeError", fmt.Sprint(v), a.Name)
+		}
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	case biosEnumeration:
+		s, ok := v.(string)
+		if !ok {
+			return 0, propertyError("Attributes/"+a.Name, "PropertyValueTypeError", fmt.Sprint(v), a.Name)
+		}
+		i := slices.Index(a.Values, s)
+		if i < 0 {
+			return 0, propertyError("Attributes/"+a.Name, "PropertyValueNotInList", s, a.Name)
+		}
+		return uint32(i), nil
+	default:
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return 0, propertyError("Attributes/"+a.Name, "PropertyValueTypeError", fmt.Sprint(v), a.Name)
+		}
+		if f < float64(a.Min) || f > float64(a.Max) {
+			return 0, propertyError("Attributes/"+a.Name, "PropertyValueOutOfRange", fmt.Sprint(v), a.Name)
+		}
+		return uint32(f), nil
+	}
+}
+
+// biosResource is the Redfish Bios resource and its settings object.
+type biosResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Settings *biosSettings `json:"@Redfish.Settings,omitempty"`
+
+	Attributes map[string]any `json:"Attributes"`
+	Id         *string        `json:"Id,omitempty"`
+	Name       *string        `json:"Name,omitempty"`
+}
+
+type biosSettings struct {
+	OdataType      *string `json:"@odata.type,omitempty"`
+	SettingsObject *IdRef  `json:"SettingsObject,omitempty"`
+}
+
+// applyBiosSettings writes the staged BIOS settings of a system into its
+// firmware image on behalf of user and returns the settings written.
+func (r *RedfishServer) applyBiosSettings(user string, sys RedfishSystem) (map[string]any, error) {
+	staged := r.state.system(sys.UnifiPort).BiosSettings
+	if len(staged) == 0 {
+		return nil, nil
+	}
+
+	err := r.updateSystemFirmware(sys, func(img *firmware.Image) error {
+		for name, v := range staged {
+			a, ok := findBiosAttribute(name)
+			if !ok {
+				continue
+			}
+			raw, err := a.raw(v)
+			if err != nil {
+				return err
+			}
+			img.Set(firmware.Uint32Variable(a.Variable, configDxeGuid, configDxeAttributes, raw))
+		}
+		return nil
+	})
+	if err == nil {
+		err = r.state.updateSystem(sys.UnifiPort, func(st *systemState) {
+			st.BiosSettings = nil
+		})
+	}
+	r.audit(user, sys, "ApplyBiosSettings", fmt.Sprint(staged), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return staged, nil
+}
+
+// restoreBiosSettings stages settings applied by applyBiosSettings again,
+// keeping any staged since.
+func (r *RedfishServer) restoreBiosSettings(sys RedfishSystem, settings map[string]any) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	return r.state.updateSystem(sys.UnifiPort, func(st *systemState) {
+		restored := maps.Clone(settings)
+		maps.Copy(restored, st.BiosSettings)
+		st.BiosSettings = restored
+	})
+}
+
+// GetBios reports the current BIOS attributes of a system, read from the
+// firmware image it is served.
+func (r *RedfishServer) GetBios(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	img, err := r.readSystemFirmware(sys)
+	if errors.Is(err, os.ErrNotExist) {
+		resourceNotFound(c, "Bios", systemId)
+		return
+	} else if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	attributes := make(map[string]any, len(biosAttributes))
+	for _, a := range biosAttributes {
+		raw := a.Default
+		if v, ok := img.Get(a.Variable, configDxeGuid); ok {
+			if u, ok := v.Uint32(); ok {
+				raw = u
+			}
+		}
+		attributes[a.Name] = a.value(raw)
+	}
+
+	c.JSON(200, &biosResource{
+		OdataId:    ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Bios", systemId)),
+		OdataType:  ptr("#Bios.v1_2_0.Bios"),
+		Id:         ptr("Bios"),
+		Name:       ptr("BIOS Configuration Current Settings"),
+		Attributes: attributes,
+		Settings: &biosSettings{
+			OdataType:      ptr("#Settings.v1_3_5.Settings"),
+			SettingsObject: &IdRef{OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Bios/Settings", systemId))},
+		},
+	})
+}
+
+func biosSettingsFor(systemId string, staged map[string]any) *biosResource {
+	if staged == nil {
+		staged = map[string]any{}
+	}
+	return &biosResource{
+		OdataId:    ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Bios/Settings", systemId)),
+		OdataType:  ptr("#Bios.v1_2_0.Bios"),
+		Id:         ptr("Settings"),
+		Name:       ptr("BIOS Configuration Pending Settings"),
+		Attributes: staged,
+	}
+}
+
+// GetBiosSettings reports the BIOS attributes staged for the next reset.
+func (r *RedfishServer) GetBiosSettings(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, biosSettingsFor(systemId, r.state.system(sys.UnifiPort).BiosSettings))
+}
+
+// SetBiosSettings stages BIOS attributes. They are written into the firmware
+// image of the system on its next reset.
+func (r *RedfishServer) SetBiosSettings(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	req := struct {
+		Attributes map[string]any `json:"Attributes"`
+	}{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	for name, v := range req.Attributes {
+		a, ok := findBiosAttribute(name)
+		if !ok {
+			invalidProperty(c, "Attributes/"+name, "PropertyUnknown", "Attributes/"+name)
+			return
+		}
+		if _, err := a.raw(v); err != nil {
+			c.JSON(400, redfishError(err))
+			return
+		}
+	}
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	err := r.state.updateSystem(sys.UnifiPort, func(st *systemState) {
+		staged := make(map[string]any, len(st.BiosSettings)+len(req.Attributes))
+		for name, v := range st.BiosSettings {
+			staged[name] = v
+		}
+		for name, v := range req.Attributes {
+			staged[name] = v
+		}
+		st.BiosSettings = staged
+	})
+	user, _ := contextUser(c)
+	r.audit(user, sys, "StageBiosSettings", fmt.Sprint(req.Attributes), err)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.JSON(200, biosSettingsFor(systemId, r.state.system(sys.UnifiPort).BiosSettings))
+}
@@ -0,0 +1,204 @@
+package redfish
+
+import (
+	"log"
+	"net"
+	"net/netip"
+	"path"
+	"slices"
+	"sync"
+)
+
+// bootSources lists the override targets a system can be booted from. Pxe and
+// UefiHttp serve the netboot chain over TFTP, Hdd refuses it so the Pi falls
+// back to its local boot media, and Cd chains iPXE to the inserted virtual
+// media.
+var bootSources = []BootSource{None, Pxe, Hdd, UefiHttp, Cd}
+
+// netbootPayload is the file whose transfer completes a netboot. A one-shot
+// override is consumed once it has been served.
+const netbootPayload = firmwareImage
+
+// systemIndex maps the MAC and IP addresses of systems to their switch port.
+// It is read by the TFTP server concurrently with the API handlers.
+type systemIndex struct {
+	mu   sync.RWMutex
+	macs map[string]int
+	ips  map[netip.Addr]int
+}
+
+func (x *systemIndex) update(systems map[int]RedfishSystem) {
+	macs := make(map[string]int)
+	ips := make(map[netip.Addr]int)
+
+	for port, sys := range systems {
+		if mac, err := net.ParseMAC(sys.MacAddress); err == nil {
+			macs[mac.String()] = port
+		}
+		if ip, err := netip.ParseAddr(sys.IpAddress); err == nil {
+			ips[ip] = port
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.macs = macs
+	x.ips = ips
+}
+
+// lookup returns the port of the system with the given MAC address, or with
+// the given IP address when mac is nil.
+func (x *systemIndex) lookup(mac net.HardwareAddr, addr netip.Addr) (int, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if mac != nil {
+		port, ok := x.macs[mac.String()]
+		return port, ok
+	}
+
+	port, ok := x.ips[addr.Unmap()]
+	return port, ok
+}
+
+// bootFor returns the Boot properties of a system from its persisted
+// override.
+func bootFor(st systemState) *Boot {
+	target := None
+	enabled := BootSourceOverrideEnabledDisabled
+
+	if st.BootSourceOverrideTarget != "" && st.BootSourceOverrideTarget != string(None) {
+		target = BootSource(st.BootSourceOverrideTarget)
+		enabled = BootSourceOverrideEnabled(st.BootSourceOverrideEnabled)
+	}
+
+	return &Boot{
+		BootSourceOverrideEnabled:                      &enabled,
+		BootSourceOverrideTarget:                       &target,
+		BootSourceOverrideTargetRedfishAllowableValues: &bootSources,
+	}
+}
+
+// applyBoot validates a requested Boot object and applies it over the
+// persisted override. An omitted BootSourceOverrideEnabled defaults to Once.
+func applyBoot(st *systemState, boot *Boot) error {
+	target := BootSource(st.BootSourceOverrideTarget)
+	if boot.BootSourceOverrideTarget != nil {
+		target = *boot.BootSourceOverrideTarget
+		if !slices.Contains(bootSources, target) {
+			return propertyError("Boot/BootSourceOverrideTarget", "PropertyValueNotInList", string(target), "BootSourceOverrideTarget")
+		}
+	}
+
+	enabled := BootSourceOverrideEnabled(st.BootSourceOverrideEnabled)
+	if boot.BootSourceOverrideEnabled != nil {
+		enabled = *boot.BootSourceOverrideEnabled
+	}
+
+	switch enabled {
+	case "":
+		enabled = BootSourceOverrideEnabledOnce
+	case BootSourceOverrideEnabledOnce, BootSourceOverrideEnabledContinuous:
+	case BootSourceOverrideEnabledDisabled:
+		target = None
+	default:
+		return propertyError("Boot/BootSourceOverrideEnabled", "PropertyValueNotInList", string(enabled), "BootSourceOverrideEnabled")
+	}
+
+	if target == "" || target == None {
+		st.BootSourceOverrideTarget = ""
+		st.BootSourceOverrideEnabled = ""
+		return nil
+	}
+
+	st.BootSourceOverrideTarget = string(target)
+	st.BootSourceOverrideEnabled = string(enabled)
+
+	return nil
+}
+
+// Netboot reports whether the TFTP server should serve the netboot chain to
+// the client with the given MAC address, or IP address when the request does
+// not carry a MAC. Clients without an override are served.
+func (r *RedfishServer) Netboot(mac net.HardwareAddr, addr netip.Addr) bool {
+	port, ok := r.index.lookup(mac, addr)
+	if !ok {
+		return true
+	}
+
+	switch BootSource(r.state.system(port).BootSourceOverrideTarget) {
+	case Hdd:
+		log.Printf("tftp: refusing netboot of system %d, boot override is %s", port, Hdd)
+		// The Pi boots from local media as soon as netboot is refused.
+		r.consumeBootOverride(port, mac)
+		return false
+	default:
+		return true
+	}
+}
+
+// NetbootServed publishes and logs every file sent to a known system and
+// consumes a one-shot netboot override once the boot payload has been sent,
+// or the iPXE script for a Cd override.
+func (r *RedfishServer) NetbootServed(mac net.HardwareAddr, addr netip.Addr, filename string) {
+	port, ok := r.index.lookup(mac, addr)
+	if !ok {
+		return
+	}
+
+	r.publishBootFile(port, filename)
+	r.logEvent(port, macString(mac), "BootFileServed", filename)
+
+	target := BootSource(r.state.system(port).BootSourceOverrideTarget)
+
+	switch path.Base(filename) {
+	case netbootPayload:
+		if target == Pxe || target == UefiHttp {
+			r.consumeBootOverride(port, mac)
+		}
+	case virtualMediaScript:
+		if target == Cd {
+			r.consumeBootOverride(port, mac)
+		}
+	}
+}
+
+// NetbootReceived logs every file written by a known system.
+func (r *RedfishServer) NetbootReceived(mac net.HardwareAddr, addr netip.Addr, filename string) {
+	port, ok := r.index.lookup(mac, addr)
+	if !ok {
+		return
+	}
+
+	r.logEvent(port, macString(mac), "FileReceived", filename)
+}
+
+func macString(mac net.HardwareAddr) string {
+	if mac == nil {
+		return ""
+	}
+	return mac.String()
+}
+
+// consumeBootOverride clears the override of the system on port if it only
+// applied to a single boot.
+func (r *RedfishServer) consumeBootOverride(port int, mac net.HardwareAddr) {
+	target := ""
+	err := r.state.updateSystem(port, func(st *systemState) {
+		if st.BootSourceOverrideEnabled != string(BootSourceOverrideEnabledOnce) {
+			return
+		}
+		target = st.BootSourceOverrideTarget
+		st.BootSourceOverrideTarget = ""
+		st.BootSourceOverrideEnabled = ""
+	})
+	if target == "" && err == nil {
+		return
+	}
+
+	// The TFTP server runs concurrently with the API, so the system is
+	// identified from the index rather than r.Systems.
+	sys := RedfishSystem{UnifiPort: port, MacAddress: macString(mac)}
+	r.audit("", sys, "ConsumeBootOverride", target, err)
+}
@@ -0,0 +1,305 @@
+package redfish
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// httpsCertificateId is the id of the single HTTPS certificate.
+const httpsCertificateId = "1"
+
+var httpsCertificatesPath = "/redfish/v1/Managers/" + serviceManagerId + "/NetworkProtocol/HTTPS/Certificates"
+
+// certificateResource is the Redfish Certificate resource.
+type certificateResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	CertificateString *string                `json:"CertificateString,omitempty"`
+	CertificateType   *string                `json:"CertificateType,omitempty"`
+	Fingerprint       *string                `json:"Fingerprint,omitempty"`
+	FingerprintHash   *string                `json:"FingerprintHashAlgorithm,omitempty"`
+	Id                *string                `json:"Id,omitempty"`
+	Issuer            *certificateIdentifier `json:"Issuer,omitempty"`
+	Name              *string                `json:"Name,omitempty"`
+	SerialNumber      *string                `json:"SerialNumber,omitempty"`
+	Subject           *certificateIdentifier `json:"Subject,omitempty"`
+	ValidNotAfter     *string                `json:"ValidNotAfter,omitempty"`
+	ValidNotBefore    *string                `json:"ValidNotBefore,omitempty"`
+}
+
+type certificateIdentifier struct {
+	CommonName   *string `json:"CommonName,omitempty"`
+	Organization *string `json:"Organization,omitempty"`
+}
+
+// certificateService is the Redfish CertificateService resource.
+type certificateService struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Actions              *certificateServiceActions `json:"Actions,omitempty"`
+	CertificateLocations *IdRef                     `json:"CertificateLocations,omitempty"`
+	Id                   *string                    `json:"Id,omitempty"`
+	Name                 *string                    `json:"Name,omitempty"`
+}
+
+type certificateServiceActions struct {
+	ReplaceCertificate *oemAction `json:"#CertificateService.ReplaceCertificate,omitempty"`
+}
+
+// replaceCertificateRequest is the body of the ReplaceCertificate action.
+// CertificateString holds the PEM encoded certificate chain followed by its
+// private key.
+type replaceCertificateRequest struct {
+	CertificateString string `json:"CertificateString"`
+	CertificateType   string `json:"CertificateType"`
+	CertificateUri    IdRef  `json:"CertificateUri"`
+}
+
+// managerNetworkProtocol is the Redfish ManagerNetworkProtocol resource.
+type managerNetworkProtocol struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	HTTPS *networkProtocol `json:"HTTPS,omitempty"`
+	Id    *string          `json:"Id,omitempty"`
+	Name  *string          `json:"Name,omitempty"`
+}
+
+type networkProtocol struct {
+	Certificates    *IdRef `json:"Certificates,omitempty"`
+	Port            *int   `json:"Port,omitempty"`
+	ProtocolEnabled *bool  `json:"ProtocolEnabled,omitempty"`
+}
+
+func certificateIdentifierFor(name pkix.Name) *certificateIdentifier {
+	return &certificateIdentifier{
+		CommonName:   ptr(name.CommonName),
+		Organization: ptr(strings.Join(name.Organization, ", ")),
+	}
+}
+
+func certificateResourceFor(cert *x509.Certificate) certificateResource {
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return certificateResource{
+		OdataId:           ptr(httpsCertificatesPath + "/" + httpsCertificateId),
+		OdataType:         ptr("#Certificate.v1_5_0.Certificate"),
+		Id:                ptr(httpsCertificateId),
+		Name:              ptr("HTTPS Certificate"),
+		CertificateType:   ptr("PEM"),
+		CertificateString: ptr(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))),
+		Fingerprint:       ptr(hex.EncodeToString(fingerprint[:])),
+		FingerprintHash:   ptr("TPM_ALG_SHA256"),
+		SerialNumber:      ptr(cert.SerialNumber.Text(16)),
+		Issuer:            certificateIdentifierFor(cert.Issuer),
+		Subject:           certificateIdentifierFor(cert.Subject),
+		ValidNotBefore:    ptr(cert.NotBefore.Format(time.RFC3339)),
+		ValidNotAfter:     ptr(cert.NotAfter.Format(time.RFC3339)),
+	}
+}
+
+// splitCertificateString separates the certificates of a PEM bundle from its
+// private key.
+func splitCertificateString(s string) (certPEM, keyPEM []byte, err error) {
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			if keyPEM != nil {
+				return nil, nil, fmt.Errorf("more than one private key")
+			}
+			keyPEM = pem.EncodeToMemory(block)
+		}
+	}
+
+	if certPEM == nil {
+		return nil, nil, fmt.Errorf("no certificate found")
+	}
+	if keyPEM == nil {
+		return nil, nil, fmt.Errorf("no private key found")
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+// requireCertificates responds 404 unless HTTPS is served with a managed
+// certificate.
+func (r *RedfishServer) requireCertificates(c *gin.Context) bool {
+	if r.Config.Certificates == nil {
+		resourceNotFound(c, "Certificate", c.Request.URL.Path)
+		return false
+	}
+	return true
+}
+
+// GetCertificateService links the certificate locations and the replace
+// action.
+func (r *RedfishServer) GetCertificateService(c *gin.Context) {
+	c.JSON(200, &certificateService{
+		OdataId:   ptr("/redfish/v1/CertificateService"),
+		OdataType: ptr("#CertificateService.v1_0_4.CertificateService"),
+		Id:        ptr("CertificateService"),
+		Name:      ptr("Certificate Service"),
+		Actions: &certificateServiceActions{
+			ReplaceCertificate: &oemAction{
+				Target: ptr("/redfish/v1/CertificateService/Actions/CertificateService.ReplaceCertificate"),
+			},
+		},
+		CertificateLocations: &IdRef{OdataId: ptr("/redfish/v1/CertificateService/CertificateLocations")},
+	})
+}
+
+// GetCertificateLocations links every certificate installed on the service.
+func (r *RedfishServer) GetCertificateLocations(c *gin.Context) {
+	certificates := []IdRef{}
+	if r.Config.Certificates != nil {
+		certificates = append(certificates, IdRef{OdataId: ptr(httpsCertificatesPath + "/" + httpsCertificateId)})
+	}
+
+	c.JSON(200, gin.H{
+		"@odata.id":   "/redfish/v1/CertificateService/CertificateLocations",
+		"@odata.type": "#CertificateLocations.v1_0_3.CertificateLocations",
+		"Id":          "CertificateLocations",
+		"Name":        "Certificate Locations",
+		"Links": gin.H{
+			"Certificates": certificates,
+		},
+	})
+}
+
+// ReplaceCertificate installs a new HTTPS certificate and key. It is served
+// from the next TLS handshake on.
+func (r *RedfishServer) ReplaceCertificate(c *gin.Context) {
+	if !r.requireCertificates(c) {
+		return
+	}
+
+	req := replaceCertificateRequest{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.CertificateType != "" && req.CertificateType != "PEM" {
+		invalidProperty(c, "CertificateType", "ActionParameterValueNotInList", req.CertificateType, "CertificateType", "CertificateService.ReplaceCertificate")
+		return
+	}
+
+	if uri := req.CertificateUri.OdataId; uri == nil || *uri != httpsCertificatesPath+"/"+httpsCertificateId {
+		value := ""
+		if uri != nil {
+			value = *uri
+		}
+		invalidProperty(c, "CertificateUri", "ActionParameterValueNotInList", value, "CertificateUri", "CertificateService.ReplaceCertificate")
+		return
+	}
+
+	certPEM, keyPEM, err := splitCertificateString(req.CertificateString)
+	if err == nil {
+		err = r.Config.Certificates.Replace(certPEM, keyPEM)
+	}
+	if err != nil {
+		c.JSON(400, redfishError(err))
+		return
+	}
+
+	log.Printf("certs: replaced %s through the CertificateService", r.Config.Certificates.CertFile)
+
+	cert, err := r.Config.Certificates.Certificate()
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	resp := certificateResourceFor(cert)
+
+	c.JSON(200, &resp)
+}
+
+// GetManagerNetworkProtocol reports the HTTPS protocol of the service manager.
+func (r *RedfishServer) GetManagerNetworkProtocol(c *gin.Context) {
+	if c.Param("managerId") != serviceManagerId {
+		resourceNotFound(c, "Manager", c.Param("managerId"))
+		return
+	}
+
+	https := &networkProtocol{
+		Port:            ptr(r.Config.Port),
+		ProtocolEnabled: ptr(r.Config.Certificates != nil),
+	}
+	if r.Config.Certificates != nil {
+		https.Certificates = &IdRef{OdataId: ptr(httpsCertificatesPath)}
+	}
+
+	c.JSON(200, &managerNetworkProtocol{
+		OdataId:   ptr("/redfish/v1/Managers/" + serviceManagerId + "/NetworkProtocol"),
+		OdataType: ptr("#ManagerNetworkProtocol.v1_5_0.ManagerNetworkProtocol"),
+		Id:        ptr("NetworkProtocol"),
+		Name:      ptr("Manager Network Protocol"),
+		HTTPS:     https,
+	})
+}
+
+// ListHttpsCertificates lists the HTTPS certificate of the service manager.
+func (r *RedfishServer) ListHttpsCertificates(c *gin.Context) {
+	if c.Param("managerId") != serviceManagerId {
+		resourceNotFound(c, "Manager", c.Param("managerId"))
+		return
+	}
+
+	if !r.requireCertificates(c) {
+		return
+	}
+
+	ids := []IdRef{
+		{OdataId: ptr(httpsCertificatesPath + "/" + httpsCertificateId)},
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#CertificateCollection.CertificateCollection"),
+		OdataType:         "#CertificateCollection.CertificateCollection",
+		Name:              ptr("HTTPS Certificate Collection"),
+		OdataId:           httpsCertificatesPath,
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// GetHttpsCertificate reports the HTTPS certificate currently served.
+func (r *RedfishServer) GetHttpsCertificate(c *gin.Context) {
+	if c.Param("managerId") != serviceManagerId || c.Param("CertificateId") != httpsCertificateId {
+		resourceNotFound(c, "Certificate", c.Param("CertificateId"))
+		return
+	}
+
+	if !r.requireCertificates(c) {
+		return
+	}
+
+	cert, err := r.Config.Certificates.Certificate()
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	resp := certificateResourceFor(cert)
+
+	c.JSON(200, &resp)
+}
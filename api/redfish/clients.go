@@ -0,0 +1,69 @@
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ubiquiti-community/go-unifi/unifi"
+)
+
+// updateClient applies fn to the controller client record of the system,
+// creating the record if the controller does not know the client yet.
+func (r *RedfishServer) updateClient(ctx context.Context, sys RedfishSystem, fn func(*unifi.User)) error {
+	if sys.MacAddress == "" {
+		return fmt.Errorf("no client MAC address known for port %d", sys.UnifiPort)
+	}
+
+	user, err := r.client.GetUserByMAC(ctx, r.Config.UnifiSite, sys.MacAddress)
+	if err != nil {
+		var notFound *unifi.NotFoundError
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("getting client %s: %w", sys.MacAddress, err)
+		}
+
+		user = &unifi.User{
+			MAC:       sys.MacAddress,
+			NetworkID: sys.NetworkID,
+		}
+		fn(user)
+
+		if _, err := r.client.CreateUser(ctx, r.Config.UnifiSite, user); err != nil {
+			return fmt.Errorf("creating client %s: %w", sys.MacAddress, err)
+		}
+		return nil
+	}
+
+	if user.NetworkID == "" {
+		user.NetworkID = sys.NetworkID
+	}
+	fn(user)
+
+	if _, err := r.client.UpdateUser(ctx, r.Config.UnifiSite, user); err != nil {
+		return fmt.Errorf("updating client %s: %w", sys.MacAddress, err)
+	}
+	return nil
+}
+
+// clientNote is the note pushed onto the controller client record of a
+// system.
+func clientNote(sys RedfishSystem, state systemState) string {
+	note := fmt.Sprintf("Redfish system %d", sys.UnifiPort)
+	if state.HostName != "" {
+		note += fmt.Sprintf(", host %s", state.HostName)
+	}
+	if state.AssetTag != "" {
+		note += fmt.Sprintf(", asset tag %s", state.AssetTag)
+	}
+	return note
+}
+
+// syncClientName pushes the name and note of state onto the controller
+// client record of the system. An empty name clears the alias; the host name
+// is only part of the note, as aliases are imported back as names.
+func (r *RedfishServer) syncClientName(ctx context.Context, sys RedfishSystem, state systemState) error {
+	return r.updateClient(ctx, sys, func(u *unifi.User) {
+		u.Name = state.Name
+		u.Note = clientNote(sys, state)
+	})
+}
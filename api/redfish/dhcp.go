@@ -0,0 +1,223 @@
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ubiquiti-community/go-unifi/unifi"
+)
+
+// dhcpBoot is the OEM resource reporting the DHCP boot options of the
+// provisioning network.
+type dhcpBoot struct {
+	OdataId *string `json:"@odata.id,omitempty"`
+
+	Actions      *dhcpBootActions `json:"Actions,omitempty"`
+	BootFilename *string          `json:"BootFilename,omitempty"`
+	BootServer   *string          `json:"BootServer,omitempty"`
+	Drift        *[]string        `json:"Drift,omitempty"`
+	Network      *string          `json:"Network,omitempty"`
+	NetworkId    *string          `json:"NetworkId,omitempty"`
+	TftpServer   *string          `json:"TftpServer,omitempty"`
+}
+
+type dhcpBootActions struct {
+	Reconcile *oemAction `json:"#Unifi.Reconcile,omitempty"`
+}
+
+func (r *RedfishServer) findNetwork(ctx context.Context, nameOrId string) (*unifi.Network, error) {
+	networks, err := r.client.ListNetwork(ctx, r.Config.UnifiSite)
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(networks, func(n unifi.Network) bool {
+		return n.ID == nameOrId || n.Name == nameOrId
+	})
+	if i == -1 {
+		return nil, fmt.Errorf("network %q not found", nameOrId)
+	}
+
+	return &networks[i], nil
+}
+
+// dhcpBootDrift lists the DHCP boot options of the network that differ from
+// the configured ones.
+func (r *RedfishServer) dhcpBootDrift(n *unifi.Network) (drift []string) {
+	if !n.DHCPDBootEnabled {
+		drift = append(drift, "dhcpd_boot_enabled is false")
+	}
+	if n.DHCPDBootServer != r.Config.DhcpBootServer {
+		drift = append(drift, fmt.Sprintf("dhcpd_boot_server is %q, want %q", n.DHCPDBootServer, r.Config.DhcpBootServer))
+	}
+	if n.DHCPDTFTPServer != r.Config.DhcpBootServer {
+		drift = append(drift, fmt.Sprintf("dhcpd_tftp_server is %q, want %q", n.DHCPDTFTPServer, r.Config.DhcpBootServer))
+	}
+	if r.Config.DhcpBootFilename != "" && n.DHCPDBootFilename != r.Config.DhcpBootFilename {
+		drift = append(drift, fmt.Sprintf("dhcpd_boot_filename is %q, want %q", n.DHCPDBootFilename, r.Config.DhcpBootFilename))
+	}
+	return
+}
+
+// reconcileDhcpBoot compares the DHCP boot options of the configured network
+// with this daemon's address and, when apply is set, corrects any drift.
+func (r *RedfishServer) reconcileDhcpBoot(ctx context.Context, apply bool) (n *unifi.Network, drift []string, err error) {
+	if r.Config.DhcpNetwork == "" || r.Config.DhcpBootServer == "" {
+		return nil, nil, fmt.Errorf("dhcp network and boot server are not configured")
+	}
+
+	n, err = r.findNetwork(ctx, r.Config.DhcpNetwork)
+	if err != nil {
+		return
+	}
+
+	drift = r.dhcpBootDrift(n)
+	if !apply || len(drift) == 0 {
+		return
+	}
+
+	n.DHCPDBootEnabled = true
+	n.DHCPDBootServer = r.Config.DhcpBootServer
+	n.DHCPDTFTPServer = r.Config.DhcpBootServer
+	if r.Config.DhcpBootFilename != "" {
+		n.DHCPDBootFilename = r.Config.DhcpBootFilename
+	}
+
+	n, err = r.client.UpdateNetwork(ctx, r.Config.UnifiSite, n)
+	if err != nil {
+		return
+	}
+
+	log.Printf("dhcp: reconciled boot options of network %s: %v", n.Name, drift)
+
+	drift = r.dhcpBootDrift(n)
+	return
+}
+
+func dhcpBootFor(n *unifi.Network, drift []string) dhcpBoot {
+	if drift == nil {
+		drift = []string{}
+	}
+	return dhcpBoot{
+		OdataId: ptr("/redfish/v1/Oem/Unifi/DhcpBoot"),
+		Actions: &dhcpBootActions{
+			Reconcile: &oemAction{
+				Target: ptr("/redfish/v1/Oem/Unifi/DhcpBoot/Actions/Unifi.Reconcile"),
+			},
+		},
+		BootFilename: ptr(n.DHCPDBootFilename),
+		BootServer:   ptr(n.DHCPDBootServer),
+		Drift:        &drift,
+		Network:      ptr(n.Name),
+		NetworkId:    ptr(n.ID),
+		TftpServer:   ptr(n.DHCPDTFTPServer),
+	}
+}
+
+// dhcpHealthInterval is how long the health check reuses a drift check, so
+// frequent probes do not reach the controller.
+const dhcpHealthInterval = time.Minute
+
+// dhcpHealth caches the last drift check of the DHCP boot options.
+type dhcpHealth struct {
+	mu      sync.Mutex
+	checked time.Time
+	drift   []string
+	err     error
+}
+
+// set records the result of a drift check.
+func (h *dhcpHealth) set(drift []string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checked, h.drift, h.err = time.Now(), drift, err
+}
+
+// checkDhcpBoot returns the drift of the DHCP boot options, checked at most
+// once per dhcpHealthInterval. Concurrent callers wait for a single check.
+func (r *RedfishServer) checkDhcpBoot(ctx context.Context) ([]string, error) {
+	h := &r.dhcpHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if time.Since(h.checked) >= dhcpHealthInterval {
+		// The result is shared, so it must not fail with the request.
+		_, h.drift, h.err = r.reconcileDhcpBoot(context.WithoutCancel(ctx), false)
+		h.checked = time.Now()
+	}
+
+	return h.drift, h.err
+}
+
+// GetDhcpBoot reports the DHCP boot options of the provisioning network and
+// how they drift from this daemon's address.
+func (r *RedfishServer) GetDhcpBoot(c *gin.Context) {
+	n, drift, err := r.reconcileDhcpBoot(c.Request.Context(), false)
+	r.dhcpHealth.set(drift, err)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	resp := dhcpBootFor(n, drift)
+
+	c.JSON(200, &resp)
+}
+
+// ReconcileDhcpBoot points the DHCP boot options of the provisioning network
+// at this daemon.
+func (r *RedfishServer) ReconcileDhcpBoot(c *gin.Context) {
+	n, drift, err := r.reconcileDhcpBoot(c.Request.Context(), true)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+	r.dhcpHealth.set(drift, nil)
+
+	resp := dhcpBootFor(n, drift)
+
+	c.JSON(200, &resp)
+}
+
+// Health reports the daemon as healthy along with whether the DHCP boot
+// options of the provisioning network drifted. The health check is served
+// without credentials, so the drift and errors are only detailed to
+// authenticated callers.
+func (r *RedfishServer) Health(c *gin.Context) {
+	resp := gin.H{"message": "OK"}
+
+	if r.Config.DhcpNetwork != "" {
+		user, _ := contextUser(c)
+		detailed := !r.authEnabled() || user != ""
+
+		drift, err := r.checkDhcpBoot(c.Request.Context())
+		dhcp := gin.H{}
+		switch {
+		case err != nil:
+			dhcp["error"] = "drift check failed"
+			if detailed {
+				dhcp["error"] = err.Error()
+			}
+		default:
+			if len(drift) > 0 {
+				resp["message"] = "DHCP boot options drifted"
+			}
+			dhcp["drifted"] = len(drift) > 0
+			if detailed {
+				if drift == nil {
+					drift = []string{}
+				}
+				dhcp["drift"] = drift
+			}
+		}
+		resp["dhcp"] = dhcp
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,196 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// extendedInfo returns a Message of @Message.ExtendedInfo. message replaces
+// the text of the registry if not empty.
+func extendedInfo(messageId, message string, args []string, related ...string) Message {
+	m := Message{MessageId: ptr(messageId)}
+
+	if def, ok := lookupMessage(messageId); ok {
+		m.Severity = ptr(def.Severity)
+		m.Resolution = ptr(def.Resolution)
+		if message == "" {
+			message = formatMessage(def.Message, args)
+		}
+	}
+
+	m.Message = ptr(message)
+	if len(args) > 0 {
+		m.MessageArgs = &args
+	}
+	if len(related) > 0 {
+		m.RelatedProperties = &related
+	}
+
+	return m
+}
+
+// redfishError returns the error response for err. Errors carrying a
+// registry message are reported with it, any other as GeneralError.
+func redfishError(err error) *RedfishError {
+	var me *messageError
+	if errors.As(err, &me) {
+		return redfishMessage(me.messageId, me.args, me.related...)
+	}
+	return redfishErrorCode(baseRegistry+"GeneralError", err)
+}
+
+// isMessage reports whether err is reported with the registry message
+// messageId.
+func isMessage(err error, messageId string) bool {
+	var me *messageError
+	return errors.As(err, &me) && me.messageId == messageId
+}
+
+// redfishErrorCode returns the error response for err, reported with the
+// given MessageId and its args.
+func redfishErrorCode(code string, err error, args ...string) *RedfishError {
+	return &RedfishError{
+		Error: RedfishErrorError{
+			Message:             ptr(err.Error()),
+			Code:                ptr(code),
+			MessageExtendedInfo: &[]Message{extendedInfo(code, err.Error(), args)},
+		},
+	}
+}
+
+// redfishMessage returns the error response for a registry message, its text
+// formatted from args.
+func redfishMessage(messageId string, args []string, related ...string) *RedfishError {
+	m := extendedInfo(messageId, "", args, related...)
+	return &RedfishError{
+		Error: RedfishErrorError{
+			Message:             m.Message,
+			Code:                m.MessageId,
+			MessageExtendedInfo: &[]Message{m},
+		},
+	}
+}
+
+// invalidProperty responds to a request body with an invalid property at the
+// JSON pointer path, reported with the Base message name and its args.
+func invalidProperty(c *gin.Context, path, name string, args ...string) {
+	c.JSON(400, redfishMessage(baseRegistry+name, args, "#/"+path))
+}
+
+// propertyMissing responds to a request body lacking required properties,
+// with a PropertyMissing message for each of them.
+func propertyMissing(c *gin.Context, properties ...string) {
+	info := make([]Message, 0, len(properties))
+	for _, p := range properties {
+		info = append(info, extendedInfo(baseRegistry+"PropertyMissing", "", []string{p}, "#/"+p))
+	}
+
+	code := *info[0].MessageId
+	message := *info[0].Message
+	if len(info) > 1 {
+		code = baseRegistry + "GeneralError"
+		message = formatMessage(baseMessages["GeneralError"].Message, nil)
+	}
+
+	c.JSON(400, &RedfishError{
+		Error: RedfishErrorError{
+			Code:                &code,
+			Message:             &message,
+			MessageExtendedInfo: &info,
+		},
+	})
+}
+
+// notImplemented responds to operations of the generated ServerInterface this
+// service does not support.
+func notImplemented(c *gin.Context) {
+	c.JSON(501, redfishMessage(baseRegistry+"ActionNotSupported", []string{c.Request.URL.Path}))
+}
+
+// resourceNotFound responds to requests for a resource of resourceType that
+// does not exist.
+func resourceNotFound(c *gin.Context, resourceType, name string) {
+	c.JSON(404, redfishMessage(baseRegistry+"ResourceNotFound", []string{resourceType, name}))
+}
+
+// methodNotAllowed responds to requests with a method the resource does not
+// support, listing those it does in the Allow header.
+func methodNotAllowed(c *gin.Context, allow ...string) {
+	c.Header("Allow", strings.Join(allow, ", "))
+	c.JSON(405, redfishMessage(baseRegistry+"OperationNotAllowed", nil))
+}
+
+// bindJSON decodes the request body into v, writing a MalformedJSON or
+// PropertyValueTypeError response if it cannot be decoded.
+func bindJSON(c *gin.Context, v any) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		property := typeErr.Field
+		if property == "" {
+			property = "the request body"
+		}
+		c.JSON(400, redfishMessage(baseRegistry+"PropertyValueTypeError", []string{typeErr.Value, property}, "#/"+strings.ReplaceAll(typeErr.Field, ".", "/")))
+	default:
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			c.JSON(400, redfishError(err))
+			return false
+		}
+		c.JSON(400, redfishMessage(baseRegistry+"MalformedJSON", nil))
+	}
+
+	return false
+}
+
+// RegisterErrorHandlers makes router answer requests for unknown resources
+// with ResourceNotFound, and requests with a method a resource does not
+// support with OperationNotAllowed and its Allow header.
+func RegisterErrorHandlers(router *gin.Engine) {
+	router.HandleMethodNotAllowed = true
+
+	router.NoRoute(func(c *gin.Context) {
+		resourceNotFound(c, "Resource", c.Request.URL.Path)
+	})
+
+	router.NoMethod(func(c *gin.Context) {
+		var allow []string
+		for _, route := range router.Routes() {
+			if matchRoute(route.Path, c.Request.URL.Path) && !slices.Contains(allow, route.Method) {
+				allow = append(allow, route.Method)
+			}
+		}
+		slices.Sort(allow)
+		methodNotAllowed(c, allow...)
+	})
+}
+
+// matchRoute reports whether path matches the route pattern of a gin route.
+func matchRoute(pattern, path string) bool {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	ss := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, p := range ps {
+		if strings.HasPrefix(p, "*") {
+			return true
+		}
+		if i >= len(ss) {
+			return false
+		}
+		if !strings.HasPrefix(p, ":") && p != ss[i] {
+			return false
+		}
+	}
+
+	return len(ps) == len(ss)
+}
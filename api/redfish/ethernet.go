@@ -0,0 +1,207 @@
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ubiquiti-community/go-unifi/unifi"
+)
+
+// ethernetInterfaceId is the id of the single NIC every system exposes.
+const ethernetInterfaceId = "eth0"
+
+// ethernetInterface is the Redfish EthernetInterface resource.
+type ethernetInterface struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	DHCPv4              *dhcpv4Configuration `json:"DHCPv4,omitempty"`
+	IPv4Addresses       *[]ipv4Address       `json:"IPv4Addresses,omitempty"`
+	IPv4StaticAddresses *[]ipv4Address       `json:"IPv4StaticAddresses,omitempty"`
+	Id                  *string              `json:"Id,omitempty"`
+	InterfaceEnabled    *bool                `json:"InterfaceEnabled,omitempty"`
+	LinkStatus          *string              `json:"LinkStatus,omitempty"`
+	MACAddress          *string              `json:"MACAddress,omitempty"`
+	Name                *string              `json:"Name,omitempty"`
+	PermanentMACAddress *string              `json:"PermanentMACAddress,omitempty"`
+	Status              *Status              `json:"Status,omitempty"`
+}
+
+type dhcpv4Configuration struct {
+	DHCPEnabled *bool `json:"DHCPEnabled,omitempty"`
+}
+
+type ipv4Address struct {
+	Address       *string `json:"Address,omitempty"`
+	AddressOrigin *string `json:"AddressOrigin,omitempty"`
+	Gateway       *string `json:"Gateway,omitempty"`
+	SubnetMask    *string `json:"SubnetMask,omitempty"`
+}
+
+func ethernetInterfaceFor(systemId string, sys RedfishSystem) ethernetInterface {
+	linkStatus := "LinkDown"
+	if sys.LinkUp {
+		linkStatus = "LinkUp"
+	}
+
+	addresses := []ipv4Address{}
+	if sys.IpAddress != "" {
+		origin := "DHCP"
+		if sys.FixedIP != "" && sys.FixedIP == sys.IpAddress {
+			origin = "Static"
+		}
+		addresses = append(addresses, ipv4Address{
+			Address:       ptr(sys.IpAddress),
+			AddressOrigin: ptr(origin),
+		})
+	}
+
+	static := []ipv4Address{}
+	if sys.FixedIP != "" {
+		static = append(static, ipv4Address{
+			Address: ptr(sys.FixedIP),
+		})
+	}
+
+	return ethernetInterface{
+		OdataId:             ptr(fmt.Sprintf("/redfish/v1/Systems/%s/EthernetInterfaces/%s", systemId, ethernetInterfaceId)),
+		OdataType:           ptr("#EthernetInterface.v1_6_0.EthernetInterface"),
+		Id:                  ptr(ethernetInterfaceId),
+		Name:                ptr("Ethernet Interface"),
+		MACAddress:          ptr(sys.MacAddress),
+		PermanentMACAddress: ptr(sys.MacAddress),
+		LinkStatus:          ptr(linkStatus),
+		InterfaceEnabled:    ptr(!sys.Quarantined),
+		DHCPv4: &dhcpv4Configuration{
+			DHCPEnabled: ptr(sys.FixedIP == ""),
+		},
+		IPv4Addresses:       &addresses,
+		IPv4StaticAddresses: &static,
+		Status: &Status{
+			State: ptr(StateEnabled),
+		},
+	}
+}
+
+// setFixedIP reserves the given IPv4 address for the client of the system on
+// the controller, or releases the reservation when address is empty.
+func (r *RedfishServer) setFixedIP(ctx context.Context, sys RedfishSystem, address string) error {
+	err := r.updateClient(ctx, sys, func(u *unifi.User) {
+		u.UseFixedIP = address != ""
+		u.FixedIP = address
+	})
+	if err != nil {
+		return err
+	}
+
+	sys.FixedIP = address
+	r.putSystem(sys)
+
+	return nil
+}
+
+// ListEthernetInterfaces lists the network interfaces of a system.
+func (r *RedfishServer) ListEthernetInterfaces(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	if _, ok := r.lookupSystem(c, systemId); !ok {
+		return
+	}
+
+	ids := []IdRef{
+		{OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/EthernetInterfaces/%s", systemId, ethernetInterfaceId))},
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#EthernetInterfaceCollection.EthernetInterfaceCollection"),
+		OdataType:         "#EthernetInterfaceCollection.EthernetInterfaceCollection",
+		Name:              ptr("Ethernet Interface Collection"),
+		OdataId:           fmt.Sprintf("/redfish/v1/Systems/%s/EthernetInterfaces", systemId),
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// GetEthernetInterface reports the MAC, addresses and link state of a system.
+func (r *RedfishServer) GetEthernetInterface(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	if c.Param("EthernetInterfaceId") != ethernetInterfaceId {
+		resourceNotFound(c, "EthernetInterface", c.Param("EthernetInterfaceId"))
+		return
+	}
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	resp := ethernetInterfaceFor(systemId, sys)
+
+	c.JSON(200, &resp)
+}
+
+// SetEthernetInterface reserves a static IPv4 address for a system through a
+// fixed IP on its controller client record.
+func (r *RedfishServer) SetEthernetInterface(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	if c.Param("EthernetInterfaceId") != ethernetInterfaceId {
+		resourceNotFound(c, "EthernetInterface", c.Param("EthernetInterfaceId"))
+		return
+	}
+
+	req := ethernetInterface{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	address := sys.FixedIP
+
+	if req.DHCPv4 != nil && req.DHCPv4.DHCPEnabled != nil && *req.DHCPv4.DHCPEnabled {
+		address = ""
+	}
+
+	if req.IPv4StaticAddresses != nil {
+		switch static := *req.IPv4StaticAddresses; len(static) {
+		case 0:
+			address = ""
+		case 1:
+			if static[0].Address == nil {
+				address = ""
+				break
+			}
+			ip, err := netip.ParseAddr(*static[0].Address)
+			if err != nil || !ip.Is4() {
+				invalidProperty(c, "IPv4StaticAddresses/0/Address", "PropertyValueFormatError", *static[0].Address, "IPv4StaticAddresses/0/Address")
+				return
+			}
+			address = ip.String()
+		default:
+			invalidProperty(c, "IPv4StaticAddresses", "ArraySizeTooLong", "IPv4StaticAddresses", "1")
+			return
+		}
+	}
+
+	if address != sys.FixedIP {
+		err := r.setFixedIP(c.Request.Context(), sys, address)
+		user, _ := contextUser(c)
+		r.audit(user, sys, "SetFixedIP", address, err)
+		if err != nil {
+			c.JSON(500, redfishError(err))
+			return
+		}
+		sys, _ = r.system(sys.UnifiPort)
+	}
+
+	resp := ethernetInterfaceFor(systemId, sys)
+
+	c.JSON(200, &resp)
+}
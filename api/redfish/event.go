@@ -0,0 +1,753 @@
+package redfish
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Event types subscriptions can select.
+const (
+	eventTypeStatusChange    = "StatusChange"
+	eventTypeResourceUpdated = "ResourceUpdated"
+	eventTypeAlert           = "Alert"
+)
+
+var eventTypes = []string{eventTypeStatusChange, eventTypeResourceUpdated, eventTypeAlert}
+
+const (
+	// signatureHeader carries the HMAC-SHA256 of the body of events sent to
+	// subscriptions with a secret.
+	signatureHeader = "X-Redfish-Signature"
+
+	defaultDeliveryRetryAttempts = 3
+	defaultDeliveryRetryInterval = 30 * time.Second
+
+	// subscriptionQueueSize bounds the events waiting for delivery to a
+	// single subscription. Events are dropped while the queue is full.
+	subscriptionQueueSize = 64
+)
+
+// eventRecord is a single event of an Event payload.
+type eventRecord struct {
+	EventId           string    `json:"EventId"`
+	EventTimestamp    string    `json:"EventTimestamp"`
+	EventType         string    `json:"EventType"`
+	Message           string    `json:"Message"`
+	MessageArgs       []string  `json:"MessageArgs,omitempty"`
+	MessageId         string    `json:"MessageId"`
+	MessageSeverity   string    `json:"MessageSeverity,omitempty"`
+	OriginOfCondition *IdRef    `json:"OriginOfCondition,omitempty"`
+	Oem               *eventOem `json:"Oem,omitempty"`
+}
+
+type eventOem struct {
+	Unifi *eventUnifiOem `json:"Unifi,omitempty"`
+}
+
+type eventUnifiOem struct {
+	BootFile *string `json:"BootFile,omitempty"`
+}
+
+// event is the payload POSTed to subscriptions.
+type event struct {
+	OdataType string        `json:"@odata.type"`
+	Context   string        `json:"Context,omitempty"`
+	Events    []eventRecord `json:"Events"`
+	Id        string        `json:"Id"`
+	Name      string        `json:"Name"`
+}
+
+// subscription is an event destination. Secret is never reported back.
+type subscription struct {
+	Destination       string   `json:"destination"`
+	Context           string   `json:"context,omitempty"`
+	EventTypes        []string `json:"event_types,omitempty"`
+	OriginResources   []string `json:"origin_resources,omitempty"`
+	Secret            string   `json:"secret,omitempty"`
+	VerifyCertificate bool     `json:"verify_certificate"`
+
+	queue  chan eventRecord
+	cancel context.CancelFunc
+}
+
+// matches reports whether an event is delivered to the subscription.
+func (s *subscription) matches(ev eventRecord) bool {
+	if len(s.EventTypes) > 0 && !slices.Contains(s.EventTypes, ev.EventType) {
+		return false
+	}
+	if len(s.OriginResources) > 0 {
+		if ev.OriginOfCondition == nil || ev.OriginOfCondition.OdataId == nil {
+			return false
+		}
+		return slices.Contains(s.OriginResources, *ev.OriginOfCondition.OdataId)
+	}
+	return true
+}
+
+// eventService persists subscriptions and the retry policy to a JSON file
+// and delivers events to them. Every subscription has its own delivery queue so a slow destination
+// does not hold up the others. An empty path keeps subscriptions in memory
+// only.
+type eventService struct {
+	mu   sync.Mutex
+	path string
+
+	// retryAttempts and retryInterval are the configured retry policy.
+	retryAttempts int
+	retryInterval time.Duration
+
+	// verifyingClient and insecureClient deliver events to subscriptions
+	// that do and do not verify the certificate of their destination.
+	verifyingClient *http.Client
+	insecureClient  *http.Client
+
+	nextEventId int
+	listeners   map[*sseListener]struct{}
+
+	// DeliveryRetryAttempts and DeliveryRetryIntervalSeconds are the retry
+	// policy set through the EventService, kept over the configured one.
+	DeliveryRetryAttempts        int `json:"delivery_retry_attempts,omitempty"`
+	DeliveryRetryIntervalSeconds int `json:"delivery_retry_interval_seconds,omitempty"`
+
+	Subscriptions map[string]*subscription `json:"subscriptions"`
+}
+
+// newEventClient returns the client delivering events, verifying the
+// certificates of destinations if verify is set. Idle connections time out
+// so those to deleted subscriptions are closed.
+func newEventClient(verify bool) *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			IdleConnTimeout: 90 * time.Second,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: !verify,
+			},
+		},
+	}
+}
+
+func loadEventService(path string, retryAttempts int, retryInterval time.Duration) (*eventService, error) {
+	if retryAttempts <= 0 {
+		retryAttempts = defaultDeliveryRetryAttempts
+	}
+	if retryInterval <= 0 {
+		retryInterval = defaultDeliveryRetryInterval
+	}
+
+	s := &eventService{
+		path:            path,
+		retryAttempts:   retryAttempts,
+		retryInterval:   retryInterval,
+		verifyingClient: newEventClient(true),
+		insecureClient:  newEventClient(false),
+		Subscriptions:   make(map[string]*subscription),
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(b, s); err != nil {
+				return nil, err
+			}
+		}
+		if s.Subscriptions == nil {
+			s.Subscriptions = make(map[string]*subscription)
+		}
+	}
+
+	for id, sub := range s.Subscriptions {
+		s.start(id, sub)
+	}
+
+	return s, nil
+}
+
+// save writes the subscriptions to a temporary file and renames it over the
+// previous one. The caller must hold mu.
+func (s *eventService) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	// Secrets are stored in the clear to sign events.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// start runs the delivery worker of a subscription.
+func (s *eventService) start(id string, sub *subscription) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub.queue = make(chan eventRecord, subscriptionQueueSize)
+	sub.cancel = cancel
+
+	client := s.insecureClient
+	if sub.VerifyCertificate {
+		client = s.verifyingClient
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-sub.queue:
+				s.deliver(ctx, client, id, sub, ev)
+			}
+		}
+	}()
+}
+
+// retryPolicy returns the retry policy set through the EventService, or the
+// configured one.
+func (s *eventService) retryPolicy() (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts, interval := s.retryAttempts, s.retryInterval
+	if s.DeliveryRetryAttempts > 0 {
+		attempts = s.DeliveryRetryAttempts
+	}
+	if s.DeliveryRetryIntervalSeconds > 0 {
+		interval = time.Duration(s.DeliveryRetryIntervalSeconds) * time.Second
+	}
+	return attempts, interval
+}
+
+// setRetryPolicy persists the retry policy set through the EventService.
+// Zero values keep the current setting.
+func (s *eventService) setRetryPolicy(attempts, intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prevAttempts, prevInterval := s.DeliveryRetryAttempts, s.DeliveryRetryIntervalSeconds
+	if attempts > 0 {
+		s.DeliveryRetryAttempts = attempts
+	}
+	if intervalSeconds > 0 {
+		s.DeliveryRetryIntervalSeconds = intervalSeconds
+	}
+
+	if err := s.save(); err != nil {
+		s.DeliveryRetryAttempts, s.DeliveryRetryIntervalSeconds = prevAttempts, prevInterval
+		return err
+	}
+	return nil
+}
+
+// deliver POSTs an event to a subscription, retrying with an exponential
+// backoff.
+func (s *eventService) deliver(ctx context.Context, client *http.Client, id string, sub *subscription, ev eventRecord) {
+	body, err := json.Marshal(event{
+		OdataType: "#Event.v1_4_0.Event",
+		Context:   sub.Context,
+		Events:    []eventRecord{ev},
+		Id:        ev.EventId,
+		Name:      "Event",
+	})
+	if err != nil {
+		log.Printf("events: encoding event %s: %v", ev.EventId, err)
+		return
+	}
+
+	attempts, interval := s.retryPolicy()
+
+	for attempt := 0; ; attempt++ {
+		err := post(ctx, client, sub, body)
+		if err == nil || ctx.Err() != nil {
+			// Delivered, or the subscription has been deleted.
+			return
+		}
+
+		if attempt >= attempts {
+			log.Printf("events: delivering event %s to subscription %s failed, giving up: %v", ev.EventId, id, err)
+			return
+		}
+
+		backoff := interval << attempt
+		log.Printf("events: delivering event %s to subscription %s failed, retrying in %s: %v", ev.EventId, id, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func post(ctx context.Context, client *http.Client, sub *subscription, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Destination, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if sub.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(sub.Secret))
+		mac.Write(body)
+		req.Header.Set(signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("destination responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// publish queues an event for every subscription and event stream it
+// matches.
+func (s *eventService) publish(ev eventRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextEventId++
+	ev.EventId = strconv.Itoa(s.nextEventId)
+	ev.EventTimestamp = time.Now().Format(time.RFC3339)
+
+	for id, sub := range s.Subscriptions {
+		if !sub.matches(ev) {
+			continue
+		}
+		select {
+		case sub.queue <- ev:
+		default:
+			log.Printf("events: queue of subscription %s is full, dropping event %s", id, ev.EventId)
+		}
+	}
+
+	for l := range s.listeners {
+		if !l.filter.matches(ev) {
+			continue
+		}
+		select {
+		case l.queue <- ev:
+		default:
+			log.Printf("events: event stream is falling behind, dropping event %s", ev.EventId)
+		}
+	}
+}
+
+func (s *eventService) create(sub *subscription) (string, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Subscriptions[id] = sub
+	if err := s.save(); err != nil {
+		delete(s.Subscriptions, id)
+		return "", err
+	}
+
+	s.start(id, sub)
+
+	return id, nil
+}
+
+func (s *eventService) get(id string) (subscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.Subscriptions[id]
+	if !ok {
+		return subscription{}, false
+	}
+	return *sub, true
+}
+
+func (s *eventService) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.Subscriptions))
+	for id := range s.Subscriptions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (s *eventService) delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.Subscriptions[id]
+	if !ok {
+		return false, nil
+	}
+
+	delete(s.Subscriptions, id)
+	if err := s.save(); err != nil {
+		s.Subscriptions[id] = sub
+		return true, err
+	}
+
+	sub.cancel()
+
+	return true, nil
+}
+
+// eventServiceResource is the Redfish EventService resource.
+type eventServiceResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Actions                      *eventServiceActions `json:"Actions,omitempty"`
+	DeliveryRetryAttempts        *int                 `json:"DeliveryRetryAttempts,omitempty"`
+	DeliveryRetryIntervalSeconds *int                 `json:"DeliveryRetryIntervalSeconds,omitempty"`
+	EventTypesForSubscription    *[]string            `json:"EventTypesForSubscription,omitempty"`
+	Id                           *string              `json:"Id,omitempty"`
+	Name                         *string              `json:"Name,omitempty"`
+	SSEFilterPropertiesSupported *sseFilterSupport    `json:"SSEFilterPropertiesSupported,omitempty"`
+	ServerSentEventUri           *string              `json:"ServerSentEventUri,omitempty"`
+	ServiceEnabled               *bool                `json:"ServiceEnabled,omitempty"`
+	Status                       *Status              `json:"Status,omitempty"`
+	Subscriptions                *IdRef               `json:"Subscriptions,omitempty"`
+}
+
+type sseFilterSupport struct {
+	EventFormatType        bool `json:"EventFormatType"`
+	EventType              bool `json:"EventType"`
+	MessageId              bool `json:"MessageId"`
+	MetricReportDefinition bool `json:"MetricReportDefinition"`
+	OriginResource         bool `json:"OriginResource"`
+	RegistryPrefix         bool `json:"RegistryPrefix"`
+	ResourceType           bool `json:"ResourceType"`
+	SubordinateResources   bool `json:"SubordinateResources"`
+}
+
+type eventServiceActions struct {
+	SubmitTestEvent *oemAction `json:"#EventService.SubmitTestEvent,omitempty"`
+}
+
+// eventDestination is the Redfish EventDestination resource.
+type eventDestination struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Context           *string              `json:"Context,omitempty"`
+	Destination       *string              `json:"Destination,omitempty"`
+	EventTypes        *[]string            `json:"EventTypes,omitempty"`
+	Id                *string              `json:"Id,omitempty"`
+	Name              *string              `json:"Name,omitempty"`
+	Oem               *eventDestinationOem `json:"Oem,omitempty"`
+	OriginResources   *[]IdRef             `json:"OriginResources,omitempty"`
+	Protocol          *string              `json:"Protocol,omitempty"`
+	VerifyCertificate *bool                `json:"VerifyCertificate,omitempty"`
+}
+
+type eventDestinationOem struct {
+	Unifi *eventDestinationUnifiOem `json:"Unifi,omitempty"`
+}
+
+type eventDestinationUnifiOem struct {
+	// Secret is the key events are signed with. It is write-only.
+	Secret *string `json:"Secret,omitempty"`
+	Signed *bool   `json:"Signed,omitempty"`
+}
+
+func eventDestinationFor(id string, sub subscription) *eventDestination {
+	origins := make([]IdRef, 0, len(sub.OriginResources))
+	for _, o := range sub.OriginResources {
+		origins = append(origins, IdRef{OdataId: ptr(o)})
+	}
+
+	eventTypes := sub.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = []string{}
+	}
+
+	return &eventDestination{
+		OdataId:           ptr("/redfish/v1/EventService/Subscriptions/" + id),
+		OdataType:         ptr("#EventDestination.v1_14_0.EventDestination"),
+		Id:                ptr(id),
+		Name:              ptr("Event Subscription " + id),
+		Context:           ptr(sub.Context),
+		Destination:       ptr(sub.Destination),
+		EventTypes:        &eventTypes,
+		OriginResources:   &origins,
+		Protocol:          ptr("Redfish"),
+		VerifyCertificate: ptr(sub.VerifyCertificate),
+		Oem: &eventDestinationOem{
+			Unifi: &eventDestinationUnifiOem{
+				Signed: ptr(sub.Secret != ""),
+			},
+		},
+	}
+}
+
+// GetEventService reports the EventService.
+func (r *RedfishServer) GetEventService(c *gin.Context) {
+	attempts, interval := r.events.retryPolicy()
+
+	c.JSON(200, &eventServiceResource{
+		OdataId:                      ptr("/redfish/v1/EventService"),
+		OdataType:                    ptr("#EventService.v1_10_0.EventService"),
+		Id:                           ptr("EventService"),
+		Name:                         ptr("Event Service"),
+		DeliveryRetryAttempts:        ptr(attempts),
+		DeliveryRetryIntervalSeconds: ptr(int(interval.Seconds())),
+		EventTypesForSubscription:    &eventTypes,
+		ServerSentEventUri:           ptr(serverSentEventUri),
+		SSEFilterPropertiesSupported: &sseFilterSupport{
+			EventType:      true,
+			MessageId:      true,
+			OriginResource: true,
+			RegistryPrefix: true,
+			ResourceType:   true,
+		},
+		ServiceEnabled: ptr(true),
+		Status:         &Status{State: ptr(StateEnabled)},
+		Subscriptions:  &IdRef{OdataId: ptr("/redfish/v1/EventService/Subscriptions")},
+		Actions: &eventServiceActions{
+			SubmitTestEvent: &oemAction{
+				Target: ptr("/redfish/v1/EventService/Actions/EventService.SubmitTestEvent"),
+			},
+		},
+	})
+}
+
+// SetEventService updates the delivery retry policy.
+func (r *RedfishServer) SetEventService(c *gin.Context) {
+	req := eventServiceResource{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Zero means the default policy when configured, so it is refused here
+	// rather than being turned into the default on the next start.
+	if req.DeliveryRetryAttempts != nil && *req.DeliveryRetryAttempts < 1 {
+		invalidProperty(c, "DeliveryRetryAttempts", "PropertyValueOutOfRange", strconv.Itoa(*req.DeliveryRetryAttempts), "DeliveryRetryAttempts")
+		return
+	}
+	if req.DeliveryRetryIntervalSeconds != nil && *req.DeliveryRetryIntervalSeconds < 1 {
+		invalidProperty(c, "DeliveryRetryIntervalSeconds", "PropertyValueOutOfRange", strconv.Itoa(*req.DeliveryRetryIntervalSeconds), "DeliveryRetryIntervalSeconds")
+		return
+	}
+
+	var attempts, intervalSeconds int
+	if req.DeliveryRetryAttempts != nil {
+		attempts = *req.DeliveryRetryAttempts
+	}
+	if req.DeliveryRetryIntervalSeconds != nil {
+		intervalSeconds = *req.DeliveryRetryIntervalSeconds
+	}
+	if err := r.events.setRetryPolicy(attempts, intervalSeconds); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	r.GetEventService(c)
+}
+
+// ListSubscriptions lists the event subscriptions.
+func (r *RedfishServer) ListSubscriptions(c *gin.Context) {
+	ids := []IdRef{}
+	for _, id := range r.events.ids() {
+		ids = append(ids, IdRef{OdataId: ptr("/redfish/v1/EventService/Subscriptions/" + id)})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#EventDestinationCollection.EventDestinationCollection"),
+		OdataType:         "#EventDestinationCollection.EventDestinationCollection",
+		Name:              ptr("Event Subscriptions Collection"),
+		OdataId:           "/redfish/v1/EventService/Subscriptions",
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// CreateSubscription subscribes a destination to events.
+func (r *RedfishServer) CreateSubscription(c *gin.Context) {
+	req := eventDestination{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Destination == nil {
+		propertyMissing(c, "Destination")
+		return
+	}
+
+	u, err := url.Parse(*req.Destination)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalidProperty(c, "Destination", "PropertyValueFormatError", *req.Destination, "Destination")
+		return
+	}
+
+	if req.Protocol != nil && *req.Protocol != "Redfish" {
+		invalidProperty(c, "Protocol", "PropertyValueNotInList", *req.Protocol, "Protocol")
+		return
+	}
+
+	sub := &subscription{
+		Destination:       *req.Destination,
+		VerifyCertificate: true,
+	}
+
+	if req.Context != nil {
+		sub.Context = *req.Context
+	}
+
+	if req.EventTypes != nil {
+		for i, t := range *req.EventTypes {
+			if !slices.Contains(eventTypes, t) {
+				invalidProperty(c, fmt.Sprintf("EventTypes/%d", i), "PropertyValueNotInList", t, "EventTypes")
+				return
+			}
+		}
+		sub.EventTypes = *req.EventTypes
+	}
+
+	if req.OriginResources != nil {
+		for i, o := range *req.OriginResources {
+			if o.OdataId == nil {
+				propertyMissing(c, fmt.Sprintf("OriginResources/%d/@odata.id", i))
+				return
+			}
+			sub.OriginResources = append(sub.OriginResources, *o.OdataId)
+		}
+	}
+
+	if req.VerifyCertificate != nil {
+		sub.VerifyCertificate = *req.VerifyCertificate
+	}
+
+	if req.Oem != nil && req.Oem.Unifi != nil && req.Oem.Unifi.Secret != nil {
+		sub.Secret = *req.Oem.Unifi.Secret
+	}
+
+	id, err := r.events.create(sub)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	log.Printf("events: subscription %s created for %s", id, sub.Destination)
+
+	s, _ := r.events.get(id)
+	c.Header("Location", "/redfish/v1/EventService/Subscriptions/"+id)
+	c.JSON(201, eventDestinationFor(id, s))
+}
+
+// GetSubscription reports an event subscription.
+func (r *RedfishServer) GetSubscription(c *gin.Context) {
+	id := c.Param("SubscriptionId")
+
+	sub, ok := r.events.get(id)
+	if !ok {
+		resourceNotFound(c, "EventDestination", c.Param("SubscriptionId"))
+		return
+	}
+
+	c.JSON(200, eventDestinationFor(id, sub))
+}
+
+// DeleteSubscription unsubscribes a destination. Events still queued for it
+// are dropped.
+func (r *RedfishServer) DeleteSubscription(c *gin.Context) {
+	ok, err := r.events.delete(c.Param("SubscriptionId"))
+	if !ok {
+		resourceNotFound(c, "EventDestination", c.Param("SubscriptionId"))
+		return
+	} else if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
+
+// SubmitTestEvent sends an event to the matching subscriptions so
+// destinations can be tested.
+func (r *RedfishServer) SubmitTestEvent(c *gin.Context) {
+	req := struct {
+		EventType         string   `json:"EventType"`
+		Message           string   `json:"Message"`
+		MessageArgs       []string `json:"MessageArgs"`
+		MessageId         string   `json:"MessageId"`
+		OriginOfCondition string   `json:"OriginOfCondition"`
+		Severity          string   `json:"Severity"`
+	}{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.EventType == "" {
+		req.EventType = eventTypeAlert
+	}
+	if !slices.Contains(eventTypes, req.EventType) {
+		invalidProperty(c, "EventType", "ActionParameterValueNotInList", req.EventType, "EventType", "EventService.SubmitTestEvent")
+		return
+	}
+
+	if req.MessageId == "" {
+		req.MessageId = resourceEventRegistry + "TestMessage"
+	}
+	if req.Message == "" {
+		req.Message = messageText(resourceEventRegistry + "TestMessage")
+	}
+	if req.Severity == "" {
+		req.Severity = string(HealthOK)
+	}
+
+	ev := eventRecord{
+		EventType:       req.EventType,
+		Message:         req.Message,
+		MessageArgs:     req.MessageArgs,
+		MessageId:       req.MessageId,
+		MessageSeverity: req.Severity,
+	}
+	if req.OriginOfCondition != "" {
+		ev.OriginOfCondition = &IdRef{OdataId: ptr(req.OriginOfCondition)}
+	}
+
+	r.events.publish(ev)
+
+	c.Status(204)
+}
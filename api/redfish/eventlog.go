@@ -0,0 +1,463 @@
+package redfish
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultEventLogMaxSize  = 1 << 20
+	defaultEventLogMaxFiles = 3
+)
+
+// logRecord is an entry of the event log of a system.
+type logRecord struct {
+	Id int `json:"id"`
+	auditEntry
+}
+
+// eventLogStore keeps an event log per system as JSON lines in a directory.
+// A log is rotated once it exceeds maxSize, keeping maxFiles rotated files.
+// An empty directory disables the event logs.
+type eventLogStore struct {
+	mu       sync.Mutex
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	// next caches the ID of the next entry of each log.
+	next map[int]int
+}
+
+func newEventLogStore(dir string, maxSize int64, maxFiles int) *eventLogStore {
+	if maxSize <= 0 {
+		maxSize = defaultEventLogMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultEventLogMaxFiles
+	}
+	return &eventLogStore{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		next:     make(map[int]int),
+	}
+}
+
+func (s *eventLogStore) enabled() bool {
+	return s.dir != ""
+}
+
+// files returns the files of the log of a system, oldest first.
+func (s *eventLogStore) files(port int) []string {
+	base := filepath.Join(s.dir, fmt.Sprintf("system-%d.jsonl", port))
+
+	files := make([]string, 0, s.maxFiles+1)
+	for i := s.maxFiles; i > 0; i-- {
+		files = append(files, fmt.Sprintf("%s.%d", base, i))
+	}
+	return append(files, base)
+}
+
+// maxLogLineSize bounds the entries read from a log. Longer lines are
+// skipped rather than failing the whole log.
+const maxLogLineSize = 1 << 20
+
+// readLogLine returns the next line of r. Lines longer than maxLogLineSize
+// are consumed and reported as tooLong without their content.
+func readLogLine(r *bufio.Reader) (line []byte, tooLong bool, err error) {
+	for {
+		frag, err := r.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(frag) > maxLogLineSize {
+				line, tooLong = nil, true
+			} else {
+				line = append(line, frag...)
+			}
+		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
+
+// read returns the entries of the log of a system, oldest first. The caller
+// must hold mu.
+func (s *eventLogStore) read(port int) ([]logRecord, error) {
+	var records []logRecord
+
+	for _, path := range s.files(port) {
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		br := bufio.NewReader(f)
+		for {
+			line, tooLong, err := readLogLine(br)
+			if tooLong {
+				log.Printf("eventlog: skipping an entry of %s longer than %d bytes", path, maxLogLineSize)
+			} else if len(bytes.TrimSpace(line)) > 0 {
+				var rec logRecord
+				// A line torn by a crash is skipped.
+				if json.Unmarshal(line, &rec) == nil {
+					records = append(records, rec)
+				}
+			}
+
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+		f.Close()
+	}
+
+	return records, nil
+}
+
+// rotate shifts the files of the log of a system, dropping the oldest. The
+// caller must hold mu.
+func (s *eventLogStore) rotate(port int) error {
+	files := s.files(port)
+
+	if err := os.Remove(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := 1; i < len(files); i++ {
+		if err := os.Rename(files[i], files[i-1]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// append adds an entry to the log of a system.
+func (s *eventLogStore) append(port int, entry auditEntry) error {
+	if !s.enabled() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, ok := s.next[port]
+	if !ok {
+		records, err := s.read(port)
+		if err != nil {
+			return err
+		}
+		next = 1
+		if len(records) > 0 {
+			next = records[len(records)-1].Id + 1
+		}
+	}
+
+	b, err := json.Marshal(logRecord{Id: next, auditEntry: entry})
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+
+	files := s.files(port)
+	current := files[len(files)-1]
+
+	if fi, err := os.Stat(current); err == nil && fi.Size()+int64(len(b)) > s.maxSize {
+		if err := s.rotate(port); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(current, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+
+	s.next[port] = next + 1
+
+	return nil
+}
+
+func (s *eventLogStore) entries(port int) ([]logRecord, error) {
+	if !s.enabled() {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(port)
+}
+
+// clear deletes the log of a system. Entry IDs keep counting up so clients
+// caching entries do not mistake new entries for old ones.
+func (s *eventLogStore) clear(port int) error {
+	if !s.enabled() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.next[port]; !ok {
+		records, err := s.read(port)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			s.next[port] = records[len(records)-1].Id + 1
+		}
+	}
+
+	for _, path := range s.files(port) {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// logEntryResource is the Redfish LogEntry resource.
+type logEntryResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Created   *string      `json:"Created,omitempty"`
+	EntryType *string      `json:"EntryType,omitempty"`
+	Id        *string      `json:"Id,omitempty"`
+	Message   *string      `json:"Message,omitempty"`
+	Name      *string      `json:"Name,omitempty"`
+	Oem       *logEntryOem `json:"Oem,omitempty"`
+	Severity  *string      `json:"Severity,omitempty"`
+}
+
+type logEntryOem struct {
+	Unifi *logEntryUnifiOem `json:"Unifi,omitempty"`
+}
+
+type logEntryUnifiOem struct {
+	Action *string `json:"Action,omitempty"`
+	Detail *string `json:"Detail,omitempty"`
+	Result *string `json:"Result,omitempty"`
+	User   *string `json:"User,omitempty"`
+}
+
+// logEntryCollection is a LogEntryCollection with its members expanded.
+type logEntryCollection struct {
+	OdataId   string `json:"@odata.id"`
+	OdataType string `json:"@odata.type"`
+
+	Members           []*logEntryResource `json:"Members"`
+	MembersOdataCount int                 `json:"Members@odata.count"`
+	Name              string              `json:"Name"`
+}
+
+// logService is the Redfish LogService resource.
+type logService struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Actions         *logServiceActions `json:"Actions,omitempty"`
+	DateTime        *string            `json:"DateTime,omitempty"`
+	Entries         *IdRef             `json:"Entries,omitempty"`
+	Id              *string            `json:"Id,omitempty"`
+	LogEntryType    *string            `json:"LogEntryType,omitempty"`
+	Name            *string            `json:"Name,omitempty"`
+	OverWritePolicy *string            `json:"OverWritePolicy,omitempty"`
+	ServiceEnabled  *bool              `json:"ServiceEnabled,omitempty"`
+	Status          *Status            `json:"Status,omitempty"`
+}
+
+type logServiceActions struct {
+	ClearLog *oemAction `json:"#LogService.ClearLog,omitempty"`
+}
+
+func eventLogPath(systemId string) string {
+	return fmt.Sprintf("/redfish/v1/Systems/%s/LogServices/EventLog", systemId)
+}
+
+func logEntryResourceFor(systemId string, rec logRecord) *logEntryResource {
+	id := strconv.Itoa(rec.Id)
+
+	message := rec.Action
+	if rec.Detail != "" {
+		message += " " + rec.Detail
+	}
+	if rec.User != "" {
+		message += " by " + rec.User
+	}
+	message += ": " + rec.Result
+
+	severity := string(HealthOK)
+	if rec.Result != auditResultOk {
+		severity = string(HealthWarning)
+	}
+
+	return &logEntryResource{
+		OdataId:   ptr(eventLogPath(systemId) + "/Entries/" + id),
+		OdataType: ptr("#LogEntry.v1_4_0.LogEntry"),
+		Id:        ptr(id),
+		Name:      ptr("Log Entry " + id),
+		Created:   ptr(rec.Time.Format(time.RFC3339)),
+		EntryType: ptr("Event"),
+		Message:   ptr(message),
+		Severity:  ptr(severity),
+		Oem: &logEntryOem{
+			Unifi: &logEntryUnifiOem{
+				Action: ptr(rec.Action),
+				Detail: ptr(rec.Detail),
+				Result: ptr(rec.Result),
+				User:   ptr(rec.User),
+			},
+		},
+	}
+}
+
+// ListLogServices lists the log services of a system.
+func (r *RedfishServer) ListLogServices(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	if _, ok := r.lookupSystem(c, systemId); !ok {
+		return
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &[]IdRef{{OdataId: ptr(eventLogPath(systemId))}},
+		OdataContext:      ptr("/redfish/v1/$metadata#LogServiceCollection.LogServiceCollection"),
+		OdataType:         "#LogServiceCollection.LogServiceCollection",
+		Name:              ptr("Log Service Collection"),
+		OdataId:           fmt.Sprintf("/redfish/v1/Systems/%s/LogServices", systemId),
+		MembersOdataCount: ptr(1),
+	})
+}
+
+// GetEventLogService reports the event log service of a system.
+func (r *RedfishServer) GetEventLogService(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	if _, ok := r.lookupSystem(c, systemId); !ok {
+		return
+	}
+
+	state := StateEnabled
+	if !r.logs.enabled() {
+		state = StateDisabled
+	}
+
+	c.JSON(200, &logService{
+		OdataId:         ptr(eventLogPath(systemId)),
+		OdataType:       ptr("#LogService.v1_1_0.LogService"),
+		Id:              ptr("EventLog"),
+		Name:            ptr("System Event Log"),
+		DateTime:        ptr(time.Now().Format(time.RFC3339)),
+		LogEntryType:    ptr("Event"),
+		OverWritePolicy: ptr("WrapsWhenFull"),
+		ServiceEnabled:  ptr(r.logs.enabled()),
+		Status:          &Status{State: ptr(state)},
+		Entries:         &IdRef{OdataId: ptr(eventLogPath(systemId) + "/Entries")},
+		Actions: &logServiceActions{
+			ClearLog: &oemAction{
+				Target: ptr(eventLogPath(systemId) + "/Actions/LogService.ClearLog"),
+			},
+		},
+	})
+}
+
+// ListEventLogEntries lists the event log entries of a system, oldest first.
+func (r *RedfishServer) ListEventLogEntries(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	records, err := r.logs.entries(sys.UnifiPort)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	members := make([]*logEntryResource, 0, len(records))
+	for _, rec := range records {
+		members = append(members, logEntryResourceFor(systemId, rec))
+	}
+
+	c.JSON(200, &logEntryCollection{
+		OdataId:           eventLogPath(systemId) + "/Entries",
+		OdataType:         "#LogEntryCollection.LogEntryCollection",
+		Name:              "System Event Log Entries",
+		Members:           members,
+		MembersOdataCount: len(members),
+	})
+}
+
+// GetEventLogEntry reports an event log entry of a system.
+func (r *RedfishServer) GetEventLogEntry(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	records, err := r.logs.entries(sys.UnifiPort)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	for _, rec := range records {
+		if strconv.Itoa(rec.Id) == c.Param("LogEntryId") {
+			c.JSON(200, logEntryResourceFor(systemId, rec))
+			return
+		}
+	}
+
+	resourceNotFound(c, "LogEntry", c.Param("LogEntryId"))
+}
+
+// ClearEventLog deletes the event log of a system. The clearing itself is
+// recorded as the first entry of the new log.
+func (r *RedfishServer) ClearEventLog(c *gin.Context) {
+	sys, ok := r.lookupSystem(c, c.Param("ComputerSystemId"))
+	if !ok {
+		return
+	}
+
+	user, _ := contextUser(c)
+	err := r.logs.clear(sys.UnifiPort)
+	r.audit(user, sys, "ClearLog", "", err)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
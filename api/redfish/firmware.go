@@ -0,0 +1,72 @@
+package redfish
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/appkins-org/go-redfish-uefi/pkg/firmware"
+)
+
+// firmwareImage is the name of the UEFI firmware image in the TFTP root.
+const firmwareImage = "RPI_EFI.fd"
+
+// macDir returns the directory of the TFTP root holding the files of the
+// system with the given MAC, named the way the Pi bootloader requests it.
+func macDir(mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", fmt.Errorf("system has no valid MAC address: %q", mac)
+	}
+	return strings.ReplaceAll(hw.String(), ":", "-"), nil
+}
+
+// systemFirmwarePath returns the path of the firmware image of a system
+// under the TFTP root.
+func (r *RedfishServer) systemFirmwarePath(sys RedfishSystem) (string, error) {
+	dir, err := macDir(sys.MacAddress)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(r.Config.TftpRootDirectory, dir, firmwareImage), nil
+}
+
+// readSystemFirmware reads the firmware image served to a system: its own
+// copy when there is one, the shared image otherwise.
+func (r *RedfishServer) readSystemFirmware(sys RedfishSystem) (*firmware.Image, error) {
+	if path, err := r.systemFirmwarePath(sys); err == nil {
+		img, err := firmware.ReadFile(path)
+		if !errors.Is(err, os.ErrNotExist) {
+			return img, err
+		}
+	}
+
+	return firmware.ReadFile(filepath.Join(r.Config.TftpRootDirectory, firmwareImage))
+}
+
+// updateSystemFirmware applies fn to the firmware image of a system and
+// writes the result to its own copy, seeded from the shared image when the
+// system has none yet.
+func (r *RedfishServer) updateSystemFirmware(sys RedfishSystem, fn func(*firmware.Image) error) error {
+	r.firmwareMu.Lock()
+	defer r.firmwareMu.Unlock()
+
+	path, err := r.systemFirmwarePath(sys)
+	if err != nil {
+		return err
+	}
+
+	img, err := r.readSystemFirmware(sys)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(img); err != nil {
+		return err
+	}
+
+	return img.WriteFile(path)
+}
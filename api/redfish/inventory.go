@@ -0,0 +1,323 @@
+package redfish
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appkins-org/go-redfish-uefi/pkg/firmware"
+	"github.com/gin-gonic/gin"
+)
+
+// bootArtifact is a file of the boot chain served over TFTP.
+type bootArtifact struct {
+	Id           string
+	File         string
+	Name         string
+	Manufacturer string
+	// Updateable reports whether SimpleUpdate installs the file.
+	Updateable bool
+	// version returns the version embedded in the file, if any.
+	version func(b []byte) (string, time.Time, bool)
+}
+
+var bootArtifacts = []bootArtifact{
+	{
+		Id:           "UEFI",
+		File:         firmwareImage,
+		Name:         "Raspberry Pi UEFI firmware",
+		Manufacturer: "Raspberry Pi",
+		Updateable:   true,
+		version: func(b []byte) (string, time.Time, bool) {
+			v, ok := firmware.UefiVersion(b)
+			return v, time.Time{}, ok
+		},
+	},
+	{
+		Id:           "Start4",
+		File:         "start4.elf",
+		Name:         "VideoCore firmware",
+		Manufacturer: "Raspberry Pi",
+		version:      firmware.VideoCoreVersion,
+	},
+	{
+		Id:           "Fixup4",
+		File:         "fixup4.dat",
+		Name:         "VideoCore memory layout",
+		Manufacturer: "Raspberry Pi",
+	},
+	{
+		Id:   "ConfigTxt",
+		File: "config.txt",
+		Name: "Boot configuration",
+	},
+}
+
+// softwareItem is a boot artifact found in the TFTP root, either shared or
+// the own copy of a system, or an image staged in the firmware repository.
+type softwareItem struct {
+	Id          string
+	Artifact    bootArtifact
+	Description string
+	Path        string
+	Sha256      string
+	Modified    time.Time
+	Version     string
+	Released    time.Time
+	// Systems are the ports of the systems the file is served to.
+	Systems []int
+
+	// size is the size of the file the item was read from.
+	size int64
+}
+
+// softwareItemCache keeps the items read from boot artifacts by path, so an
+// inventory only reads and hashes the files changed since the last one.
+type softwareItemCache struct {
+	mu    sync.Mutex
+	items map[string]softwareItem
+}
+
+// read returns the item of the artifact a at path, read again only if its
+// size or modification time changed.
+func (sc *softwareItemCache) read(id, path string, a bootArtifact) (softwareItem, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return softwareItem{}, err
+	}
+
+	sc.mu.Lock()
+	item, ok := sc.items[path]
+	sc.mu.Unlock()
+	if ok && item.Id == id && item.size == fi.Size() && item.Modified.Equal(fi.ModTime()) {
+		return item, nil
+	}
+
+	if item, err = readSoftwareItem(id, path, fi, a); err != nil {
+		return softwareItem{}, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.items == nil {
+		sc.items = make(map[string]softwareItem)
+	}
+	sc.items[path] = item
+
+	return item, nil
+}
+
+func readSoftwareItem(id, path string, fi os.FileInfo, a bootArtifact) (softwareItem, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return softwareItem{}, err
+	}
+
+	sum := sha256.Sum256(b)
+	item := softwareItem{
+		Id:          id,
+		Artifact:    a,
+		Description: a.File,
+		Path:        path,
+		Sha256:      hex.EncodeToString(sum[:]),
+		Modified:    fi.ModTime(),
+		size:        fi.Size(),
+	}
+
+	if a.version != nil {
+		if v, released, ok := a.version(b); ok {
+			item.Version = v
+			item.Released = released
+		} else {
+			log.Printf("inventory: no %s version found in %s", a.Id, path)
+		}
+	}
+	if item.Version == "" {
+		// Files without an embedded version are told apart by content.
+		item.Version = "sha256:" + item.Sha256[:16]
+	}
+
+	return item, nil
+}
+
+// softwareInventory returns the boot artifacts of the TFTP root followed by
+// the images staged in the firmware repository. Shared files relate to the
+// systems without their own copy.
+func (r *RedfishServer) softwareInventory() ([]softwareItem, error) {
+	root := r.Config.TftpRootDirectory
+	systems := r.systems()
+	ports := slices.Sorted(maps.Keys(systems))
+
+	var items []softwareItem
+	for _, a := range bootArtifacts {
+		var shared []int
+		var own []softwareItem
+
+		for _, port := range ports {
+			dir, err := macDir(systems[port].MacAddress)
+			if err != nil {
+				shared = append(shared, port)
+				continue
+			}
+
+			item, err := r.softwareItems.read(fmt.Sprintf("%d-%s", port, a.Id), filepath.Join(root, dir, a.File), a)
+			if errors.Is(err, os.ErrNotExist) {
+				shared = append(shared, port)
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			item.Systems = []int{port}
+			own = append(own, item)
+		}
+
+		item, err := r.softwareItems.read("Shared-"+a.Id, filepath.Join(root, a.File), a)
+		switch {
+		case err == nil:
+			item.Systems = shared
+			items = append(items, item)
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+
+		items = append(items, own...)
+	}
+
+	for _, img := range r.repository.list() {
+		items = append(items, repositoryItem(img, r.repository.path(img.Id)))
+	}
+
+	return items, nil
+}
+
+// softwareInventoryResource extends the generated SoftwareInventory with
+// the properties served by this implementation.
+type softwareInventoryResource struct {
+	SoftwareInventory
+
+	Oem *softwareInventoryOem `json:"Oem,omitempty"`
+}
+
+type softwareInventoryOem struct {
+	Unifi *softwareInventoryUnifiOem `json:"Unifi,omitempty"`
+}
+
+type softwareInventoryUnifiOem struct {
+	LastModified *string `json:"LastModified,omitempty"`
+	Path         *string `json:"Path,omitempty"`
+	Sha256       *string `json:"Sha256,omitempty"`
+}
+
+func softwareInventoryPath(id string) string {
+	return "/redfish/v1/UpdateService/FirmwareInventory/" + id
+}
+
+func softwareInventoryFor(root string, item softwareItem) *softwareInventoryResource {
+	related := make([]IdRef, 0, len(item.Systems))
+	for _, port := range item.Systems {
+		related = append(related, IdRef{OdataId: ptr(systemPath(port))})
+	}
+
+	path, err := filepath.Rel(root, item.Path)
+	if err != nil || strings.HasPrefix(path, "..") {
+		path = item.Path
+	}
+
+	resp := &softwareInventoryResource{
+		SoftwareInventory: SoftwareInventory{
+			OdataId:               ptr(softwareInventoryPath(item.Id)),
+			OdataType:             ptr("#SoftwareInventory.v1_2_3.SoftwareInventory"),
+			Id:                    ptr(item.Id),
+			Name:                  ptr(item.Artifact.Name),
+			Description:           ptr(item.Description),
+			SoftwareId:            ptr(item.Artifact.Id),
+			Version:               ptr(item.Version),
+			Updateable:            ptr(item.Artifact.Updateable),
+			RelatedItem:           &related,
+			RelatedItemOdataCount: ptr(len(related)),
+			Status:                &Status{State: ptr(StateEnabled), Health: ptr(HealthOK)},
+		},
+		Oem: &softwareInventoryOem{
+			Unifi: &softwareInventoryUnifiOem{
+				LastModified: ptr(item.Modified.UTC().Format(time.RFC3339)),
+				Path:         ptr(path),
+				Sha256:       ptr(item.Sha256),
+			},
+		},
+	}
+
+	if item.Artifact.Manufacturer != "" {
+		resp.Manufacturer = ptr(item.Artifact.Manufacturer)
+	}
+	if !item.Released.IsZero() {
+		resp.ReleaseDate = ptr(item.Released)
+	}
+	if len(item.Systems) == 0 {
+		// Staged images and shared files every system has its own copy of
+		// are not served.
+		resp.Status.State = ptr(StateStandbyOffline)
+	}
+
+	return resp
+}
+
+// FirmwareInventory implements ServerInterface. Every boot artifact of the
+// TFTP root is listed, shared files and the own copies of systems alike.
+func (r *RedfishServer) FirmwareInventory(c *gin.Context) {
+	if err := r.refreshSystems(c.Request.Context()); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	items, err := r.softwareInventory()
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	ids := make([]IdRef, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, IdRef{OdataId: ptr(softwareInventoryPath(item.Id))})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#SoftwareInventoryCollection.SoftwareInventoryCollection"),
+		OdataType:         "#SoftwareInventoryCollection.SoftwareInventoryCollection",
+		Name:              ptr("Firmware Inventory Collection"),
+		OdataId:           "/redfish/v1/UpdateService/FirmwareInventory",
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// GetSoftwareInventory implements ServerInterface.
+func (r *RedfishServer) GetSoftwareInventory(c *gin.Context, softwareId string) {
+	if err := r.refreshSystems(c.Request.Context()); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	items, err := r.softwareInventory()
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	for _, item := range items {
+		if item.Id == softwareId {
+			c.JSON(200, softwareInventoryFor(r.Config.TftpRootDirectory, item))
+			return
+		}
+	}
+
+	resourceNotFound(c, "SoftwareInventory", softwareId)
+}
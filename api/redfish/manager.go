@@ -0,0 +1,100 @@
+package redfish
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serviceManagerId is the id of the manager representing this daemon.
+const serviceManagerId = "1"
+
+// systemManagerPrefix prefixes the ids of the per-system managers.
+const systemManagerPrefix = "System."
+
+func systemManagerId(systemId string) string {
+	return systemManagerPrefix + systemId
+}
+
+// systemIds returns the ids of all systems in ascending order.
+func (r *RedfishServer) systemIds() []int {
+	return slices.Sorted(maps.Keys(r.systems()))
+}
+
+func (r *RedfishServer) serviceManager() Manager {
+	now := time.Now()
+
+	ids := r.systemIds()
+	servers := make([]IdRef, 0, len(ids))
+	for _, i := range ids {
+		servers = append(servers, IdRef{OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%d", i))})
+	}
+
+	return Manager{
+		OdataContext:        ptr("/redfish/v1/$metadata#Manager.Manager"),
+		OdataId:             ptr("/redfish/v1/Managers/" + serviceManagerId),
+		OdataType:           ptr("#Manager.v1_10_0.Manager"),
+		Id:                  ptr(serviceManagerId),
+		Name:                ptr("go-redfish-uefi"),
+		Description:         ptr("Redfish service managing PoE powered UEFI systems"),
+		Model:               ptr("go-redfish-uefi"),
+		ManagerType:         ptr(ManagerTypeService),
+		FirmwareVersion:     ptr(r.Config.FirmwareVersion),
+		DateTime:            ptr(now.Format(time.RFC3339)),
+		DateTimeLocalOffset: ptr(now.Format("-07:00")),
+		PowerState:          ptr(On),
+		Status: &Status{
+			State:  ptr(StateEnabled),
+			Health: ptr(HealthOK),
+		},
+		Links: &ManagerLinks{
+			ManagerForServers: &servers,
+		},
+	}
+}
+
+// systemManager returns the manager view of a single system, backed by its
+// switch port.
+func (r *RedfishServer) systemManager(systemId string, sys RedfishSystem) Manager {
+	now := time.Now()
+	managerId := systemManagerId(systemId)
+
+	return Manager{
+		OdataContext:        ptr("/redfish/v1/$metadata#Manager.Manager"),
+		OdataId:             ptr("/redfish/v1/Managers/" + managerId),
+		OdataType:           ptr("#Manager.v1_10_0.Manager"),
+		Id:                  ptr(managerId),
+		Name:                ptr(fmt.Sprintf("Manager for System %s", systemId)),
+		Description:         ptr(fmt.Sprintf("PoE port %d of switch %s", sys.UnifiPort, sys.DeviceMac)),
+		Model:               ptr("go-redfish-uefi"),
+		ManagerType:         ptr(ManagerTypeBMC),
+		FirmwareVersion:     ptr(r.Config.FirmwareVersion),
+		DateTime:            ptr(now.Format(time.RFC3339)),
+		DateTimeLocalOffset: ptr(now.Format("-07:00")),
+		PowerState:          ptr(On),
+		Status: &Status{
+			State:  ptr(StateEnabled),
+			Health: ptr(HealthOK),
+		},
+		Links: &ManagerLinks{
+			ManagerForServers: &[]IdRef{{OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%s", systemId))}},
+		},
+		VirtualMedia: &IdRef{OdataId: ptr("/redfish/v1/Managers/" + managerId + "/VirtualMedia")},
+	}
+}
+
+// parseSystemManagerId returns the system id of a per-system manager id.
+func parseSystemManagerId(managerId string) (int, bool) {
+	id, ok := strings.CutPrefix(managerId, systemManagerPrefix)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
@@ -0,0 +1,183 @@
+package redfish
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ubiquiti-community/go-unifi/unifi"
+)
+
+// Model families systems are classified into.
+const (
+	ModelFamilyPi4   = "pi4"
+	ModelFamilyPi5   = "pi5"
+	ModelFamilyCM4   = "cm4"
+	ModelFamilyOther = "other"
+)
+
+// SystemModel describes the hardware of a model family.
+type SystemModel struct {
+	Model          string  `yaml:"model" mapstructure:"model"`
+	Manufacturer   string  `yaml:"manufacturer" mapstructure:"manufacturer"`
+	ProcessorModel string  `yaml:"processor_model" mapstructure:"processor_model"`
+	ProcessorCount int     `yaml:"processor_count" mapstructure:"processor_count"`
+	CoreCount      int     `yaml:"core_count" mapstructure:"core_count"`
+	MemoryGiB      float32 `yaml:"memory_gib" mapstructure:"memory_gib"`
+}
+
+// merge returns m with the non-zero fields of o applied over it.
+func (m SystemModel) merge(o SystemModel) SystemModel {
+	if o.Model != "" {
+		m.Model = o.Model
+	}
+	if o.Manufacturer != "" {
+		m.Manufacturer = o.Manufacturer
+	}
+	if o.ProcessorModel != "" {
+		m.ProcessorModel = o.ProcessorModel
+	}
+	if o.ProcessorCount != 0 {
+		m.ProcessorCount = o.ProcessorCount
+	}
+	if o.CoreCount != 0 {
+		m.CoreCount = o.CoreCount
+	}
+	if o.MemoryGiB != 0 {
+		m.MemoryGiB = o.MemoryGiB
+	}
+	return m
+}
+
+var systemModels = map[string]SystemModel{
+	ModelFamilyPi4: {
+		Model:          "Raspberry Pi 4 Model B",
+		Manufacturer:   "Raspberry Pi Ltd",
+		ProcessorModel: "Broadcom BCM2711",
+		ProcessorCount: 1,
+		CoreCount:      4,
+		MemoryGiB:      4,
+	},
+	ModelFamilyPi5: {
+		Model:          "Raspberry Pi 5",
+		Manufacturer:   "Raspberry Pi Ltd",
+		ProcessorModel: "Broadcom BCM2712",
+		ProcessorCount: 1,
+		CoreCount:      4,
+		MemoryGiB:      8,
+	},
+	ModelFamilyCM4: {
+		Model:          "Raspberry Pi Compute Module 4",
+		Manufacturer:   "Raspberry Pi Ltd",
+		ProcessorModel: "Broadcom BCM2711",
+		ProcessorCount: 1,
+		CoreCount:      4,
+		MemoryGiB:      4,
+	},
+	ModelFamilyOther: {
+		Model:        "Raspberry Pi",
+		Manufacturer: "Raspberry Pi Ltd",
+	},
+}
+
+// raspberryPiOUIs maps the OUIs assigned to the Raspberry Pi Foundation and
+// Raspberry Pi Trading to the model family most likely behind them.
+var raspberryPiOUIs = map[string]string{
+	"b8:27:eb": ModelFamilyOther, // Raspberry Pi Foundation, Pi 3 and earlier
+	"dc:a6:32": ModelFamilyPi4,   // Raspberry Pi Trading
+	"e4:5f:01": ModelFamilyPi4,   // Raspberry Pi Trading
+	"28:cd:c1": ModelFamilyPi4,   // Raspberry Pi Trading
+	"d8:3a:dd": ModelFamilyPi4,   // Raspberry Pi Trading
+	"2c:cf:67": ModelFamilyPi5,   // Raspberry Pi Trading
+}
+
+// nonHostOUIs lists the OUIs of access point and IP camera vendors, whose
+// clients are never hosts.
+var nonHostOUIs = []string{
+	"00:40:8c", "ac:cc:8e", "b8:a4:4f", // Axis Communications
+	"28:57:be", "44:19:b6", "4c:bd:8f", "bc:ad:28", "c0:56:e3", // Hikvision
+	"3c:ef:8c", "90:02:a9", "e0:50:8b", // Dahua
+	"00:0b:86", "24:de:c6", "94:b4:0f", // Aruba
+	"2c:5d:93", "c4:01:7c", // Ruckus
+	"00:18:0a", "88:15:44", // Cisco Meraki
+	"18:e8:29", "24:5a:4c", "68:d7:9a", "74:83:c2", "78:8a:20", "f0:9f:c2", "fc:ec:da", // Ubiquiti
+}
+
+// classifyClient returns the model family of an active client and whether it
+// is a host at all. UniFi devices, clients with the OUI of an access point or
+// camera vendor and clients whose fingerprint category is excluded are not
+// hosts.
+func (r *RedfishServer) classifyClient(c unifi.ActiveClient) (family string, host bool) {
+	if c.UnifiDevice {
+		return "", false
+	}
+
+	mac := strings.ToLower(c.Mac)
+	for _, oui := range slices.Concat(nonHostOUIs, r.Config.ExcludedOUIs) {
+		if strings.HasPrefix(mac, strings.ToLower(oui)) {
+			return "", false
+		}
+	}
+
+	for _, cat := range r.Config.ExcludedDeviceCategories {
+		if c.Fingerprint.DevCat == cat {
+			return "", false
+		}
+	}
+
+	// Fingerprints configured by the operator take precedence over the OUI.
+	if family, ok := r.Config.FingerprintModels[strconv.Itoa(c.Fingerprint.DevID)]; ok && c.Fingerprint.DevID != 0 {
+		return family, true
+	}
+
+	if len(mac) < 8 {
+		return "", true
+	}
+
+	family, ok := raspberryPiOUIs[mac[:8]]
+	if !ok {
+		return "", true
+	}
+
+	// The DHCP hostname disambiguates OUIs shared between model families.
+	hostname := strings.ToLower(c.Hostname)
+	switch {
+	case strings.Contains(hostname, "cm4"):
+		family = ModelFamilyCM4
+	case strings.Contains(hostname, "pi5"):
+		family = ModelFamilyPi5
+	case strings.Contains(hostname, "pi4"):
+		family = ModelFamilyPi4
+	}
+
+	return family, true
+}
+
+// modelFamily returns the configured model family of a system, falling back
+// to the detected one.
+func (r *RedfishServer) modelFamily(sys RedfishSystem) string {
+	if conf, ok := r.Config.Systems[strconv.Itoa(sys.UnifiPort)]; ok && conf.ModelFamily != "" {
+		return conf.ModelFamily
+	}
+	return sys.ModelFamily
+}
+
+// systemModel returns the hardware description of a system, applying the
+// configured table and per-system overrides over the built-in model table.
+func (r *RedfishServer) systemModel(sys RedfishSystem) (model SystemModel, ok bool) {
+	family := r.modelFamily(sys)
+	conf, hasConf := r.Config.Systems[strconv.Itoa(sys.UnifiPort)]
+
+	if family != "" {
+		model, ok = systemModels[family]
+		if o, found := r.Config.Models[family]; found {
+			model, ok = model.merge(o), true
+		}
+	}
+
+	if hasConf && conf.Model != (SystemModel{}) {
+		model, ok = model.merge(conf.Model), true
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+package redfish
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultControllerPollInterval = 30 * time.Second
+
+// controllerMonitor tracks what was last seen of the controller so changes
+// are published as events once, whether they were made through this API or
+// out of band.
+type controllerMonitor struct {
+	mu          sync.Mutex
+	unreachable bool
+	poeModes    map[int]string
+}
+
+func systemPath(port int) string {
+	return "/redfish/v1/Systems/" + strconv.Itoa(port)
+}
+
+// notePoeMode records the PoE mode of a port, logging the change and
+// publishing a power transition of its system when it changed. The first mode
+// seen of a port is only recorded.
+func (r *RedfishServer) notePoeMode(port int, poeMode string) {
+	r.monitor.mu.Lock()
+	if r.monitor.poeModes == nil {
+		r.monitor.poeModes = make(map[int]string)
+	}
+	prev, seen := r.monitor.poeModes[port]
+	r.monitor.poeModes[port] = poeMode
+	r.monitor.mu.Unlock()
+
+	if seen && prev != poeMode {
+		r.logEvent(port, "", "PortPoeModeChanged", prev+" -> "+poeMode)
+	}
+
+	before := (&RedfishSystem{PoeMode: prev}).GetPowerState()
+	after := (&RedfishSystem{PoeMode: poeMode}).GetPowerState()
+
+	if seen && *before != *after {
+		r.publishPowerState(port, string(*after))
+	}
+}
+
+func (r *RedfishServer) publishPowerState(port int, state string) {
+	path := systemPath(port)
+	r.events.publish(eventRecord{
+		EventType:         eventTypeStatusChange,
+		MessageId:         resourceEventRegistry + "ResourceStateChanged",
+		Message:           messageText(resourceEventRegistry+"ResourceStateChanged", path, state),
+		MessageArgs:       []string{path, state},
+		MessageSeverity:   string(HealthOK),
+		OriginOfCondition: &IdRef{OdataId: ptr(path)},
+	})
+}
+
+// publishBootFile publishes that a system fetched a file of the netboot
+// chain.
+func (r *RedfishServer) publishBootFile(port int, filename string) {
+	path := systemPath(port)
+	r.events.publish(eventRecord{
+		EventType:         eventTypeResourceUpdated,
+		MessageId:         resourceEventRegistry + "ResourceChanged",
+		Message:           messageText(resourceEventRegistry + "ResourceChanged"),
+		MessageSeverity:   string(HealthOK),
+		OriginOfCondition: &IdRef{OdataId: ptr(path)},
+		Oem: &eventOem{
+			Unifi: &eventUnifiOem{BootFile: ptr(filename)},
+		},
+	})
+}
+
+// noteController records whether the controller answered, publishing an
+// alert when it becomes unreachable and a status change once it recovers.
+func (r *RedfishServer) noteController(err error) {
+	r.monitor.mu.Lock()
+	changed := r.monitor.unreachable != (err != nil)
+	r.monitor.unreachable = err != nil
+	r.monitor.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	path := "/redfish/v1/Managers/" + serviceManagerId
+
+	if err != nil {
+		log.Printf("events: controller %s unreachable: %v", r.Config.UnifiEndpoint, err)
+		args := []string{r.Config.UnifiEndpoint, err.Error()}
+		r.events.publish(eventRecord{
+			EventType:         eventTypeAlert,
+			MessageId:         unifiRegistry + "ControllerUnreachable",
+			Message:           messageText(unifiRegistry+"ControllerUnreachable", args...),
+			MessageArgs:       args,
+			MessageSeverity:   string(HealthCritical),
+			OriginOfCondition: &IdRef{OdataId: ptr(path)},
+		})
+		return
+	}
+
+	log.Printf("events: controller %s reachable again", r.Config.UnifiEndpoint)
+	r.events.publish(eventRecord{
+		EventType:         eventTypeStatusChange,
+		MessageId:         resourceEventRegistry + "ResourceStatusChangedOK",
+		Message:           messageText(resourceEventRegistry+"ResourceStatusChangedOK", path, string(HealthOK)),
+		MessageArgs:       []string{path, string(HealthOK)},
+		MessageSeverity:   string(HealthOK),
+		OriginOfCondition: &IdRef{OdataId: ptr(path)},
+	})
+}
+
+// Monitor polls the controller until ctx is done so power transitions made
+// out of band and controller outages are published without a client asking.
+func (r *RedfishServer) Monitor(ctx context.Context) {
+	interval := r.Config.ControllerPollInterval
+	if interval <= 0 {
+		interval = defaultControllerPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.pollController(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *RedfishServer) pollController(ctx context.Context) {
+	device, err := r.client.GetDeviceByMAC(ctx, r.Config.UnifiSite, r.Config.UnifiDevice)
+	r.noteController(err)
+	if err != nil {
+		return
+	}
+
+	for _, port := range device.PortOverrides {
+		r.notePoeMode(port.PortIDX, port.PoeMode)
+	}
+}
@@ -0,0 +1,156 @@
+package redfish
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ubiquiti-community/go-unifi/unifi"
+)
+
+type setNetworkModeRequest struct {
+	NetworkMode string `json:"NetworkMode"`
+}
+
+// networkModes returns the port profiles, by name or ID, that each network
+// mode of the system maps to. Per-system modes take precedence over the
+// globally configured ones.
+func (r *RedfishServer) networkModes(sys RedfishSystem) map[string]string {
+	modes := make(map[string]string)
+	maps.Copy(modes, r.Config.NetworkModes)
+	if conf, ok := r.Config.Systems[strconv.Itoa(sys.UnifiPort)]; ok {
+		maps.Copy(modes, conf.NetworkModes)
+	}
+	return modes
+}
+
+// resolvePortProfile finds the port profile matching the given name or ID.
+func resolvePortProfile(profiles []unifi.PortProfile, nameOrId string) (unifi.PortProfile, bool) {
+	i := slices.IndexFunc(profiles, func(p unifi.PortProfile) bool {
+		return p.ID == nameOrId || p.Name == nameOrId
+	})
+	if i == -1 {
+		return unifi.PortProfile{}, false
+	}
+	return profiles[i], true
+}
+
+// networkMode returns the mode whose port profile is applied to the port of
+// the system, or an empty string if the port uses an unmapped profile. Modes
+// are matched in sorted order so profiles mapped to several modes always
+// report the same one.
+func (r *RedfishServer) networkMode(ctx context.Context, sys RedfishSystem) (string, error) {
+	modes := r.networkModes(sys)
+	if len(modes) == 0 {
+		return "", nil
+	}
+
+	profiles, err := r.client.ListPortProfile(ctx, r.Config.UnifiSite)
+	if err != nil {
+		return "", err
+	}
+
+	for _, mode := range slices.Sorted(maps.Keys(modes)) {
+		if p, ok := resolvePortProfile(profiles, modes[mode]); ok && p.ID == sys.PortProfileID {
+			return mode, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (r *RedfishServer) updateDevicePortProfile(ctx context.Context, portIdx int, profileId string) (device *unifi.Device, err error) {
+	r.deviceMu.Lock()
+	defer r.deviceMu.Unlock()
+
+	device, err = r.client.GetDeviceByMAC(ctx, r.Config.UnifiSite, r.Config.UnifiDevice)
+	if err != nil {
+		return
+	}
+	i := slices.IndexFunc(device.PortOverrides, func(p unifi.DevicePortOverrides) bool {
+		return p.PortIDX == portIdx
+	})
+	if i < 0 {
+		return nil, newMessageError(unifiRegistry+"PortNotFound", strconv.Itoa(portIdx), device.MAC)
+	}
+	device.PortOverrides[i].PortProfileID = profileId
+
+	device, err = r.client.UpdateDevice(ctx, r.Config.UnifiSite, device)
+	return
+}
+
+// setNetworkMode applies the port profile mapped to mode to the port of the
+// system.
+func (r *RedfishServer) setNetworkMode(ctx context.Context, sys RedfishSystem, mode string) error {
+	nameOrId, ok := r.networkModes(sys)[mode]
+	if !ok {
+		return propertyError("NetworkMode", "ActionParameterValueNotInList", mode, "NetworkMode", "Unifi.SetNetworkMode")
+	}
+
+	profiles, err := r.client.ListPortProfile(ctx, r.Config.UnifiSite)
+	if err != nil {
+		return err
+	}
+
+	profile, ok := resolvePortProfile(profiles, nameOrId)
+	if !ok {
+		return newMessageError(baseRegistry+"ResourceNotFound", "PortProfile", nameOrId)
+	}
+
+	if profile.ID == sys.PortProfileID {
+		return nil
+	}
+
+	if _, err := r.updateDevicePortProfile(ctx, sys.UnifiPort, profile.ID); err != nil {
+		return err
+	}
+
+	sys.PortProfileID = profile.ID
+	r.putSystem(sys)
+
+	return nil
+}
+
+// SetNetworkMode switches the port profile of a system to the one mapped to
+// the requested network mode.
+func (r *RedfishServer) SetNetworkMode(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	req := setNetworkModeRequest{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	if _, ok := r.networkModes(sys)[req.NetworkMode]; !ok {
+		invalidProperty(c, "NetworkMode", "ActionParameterValueNotInList", req.NetworkMode, "NetworkMode", "Unifi.SetNetworkMode")
+		return
+	}
+
+	// Moving a quarantined port would break its isolation, and the release
+	// would then restore the profile it had before over the new one.
+	if r.state.system(sys.UnifiPort).QuarantineProfileID != "" {
+		c.JSON(409, redfishMessage(unifiRegistry+"SystemQuarantined", []string{systemId}))
+		return
+	}
+
+	err := r.setNetworkMode(c.Request.Context(), sys, req.NetworkMode)
+	user, _ := contextUser(c)
+	r.audit(user, sys, "SetNetworkMode", req.NetworkMode, err)
+	switch {
+	case isMessage(err, baseRegistry+"ActionParameterValueNotInList"), isMessage(err, baseRegistry+"ResourceNotFound"):
+		c.JSON(400, redfishError(err))
+		return
+	case err != nil:
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
@@ -0,0 +1,173 @@
+package redfish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const poeBudgetExceededCode = unifiRegistry + "PoEBudgetExceeded"
+
+// unifiOem holds the OEM properties accepted on power requests.
+type unifiOem struct {
+	PoEBudgetOverride bool `json:"PoEBudgetOverride,omitempty"`
+}
+
+type oemRequest struct {
+	Unifi *unifiOem `json:"Unifi,omitempty"`
+}
+
+func (o *oemRequest) poeBudgetOverride() bool {
+	if o == nil || o.Unifi == nil {
+		return false
+	}
+	return o.Unifi.PoEBudgetOverride
+}
+
+type resetRequest struct {
+	ResetRequestBody
+	Oem *oemRequest `json:"Oem,omitempty"`
+}
+
+// flexFloat decodes numbers the controller reports either as JSON numbers or
+// as quoted strings.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+type devicePortStats struct {
+	PortIDX   int       `json:"port_idx"`
+	PoeEnable bool      `json:"poe_enable"`
+	PoePower  flexFloat `json:"poe_power"`
+}
+
+type deviceStats struct {
+	MAC           string            `json:"mac"`
+	TotalMaxPower flexFloat         `json:"total_max_power"`
+	PortTable     []devicePortStats `json:"port_table"`
+}
+
+// statDevice reads the live statistics of the configured switch. The unifi
+// client does not expose them, so the stat endpoint is queried directly using
+// the authenticated http client, trying the UniFi OS path before the classic
+// controller path.
+func (r *RedfishServer) statDevice(ctx context.Context) (stats *deviceStats, err error) {
+	base := strings.TrimSuffix(r.Config.UnifiEndpoint, "/")
+
+	for _, prefix := range []string{"/proxy/network/api", "/api"} {
+		url := fmt.Sprintf("%s%s/s/%s/stat/device/%s", base, prefix, r.Config.UnifiSite, r.Config.UnifiDevice)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := r.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %s for %s", resp.Status, url)
+		}
+
+		body := struct {
+			Data []deviceStats `json:"data"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding device stats: %w", err)
+		}
+
+		if len(body.Data) == 0 {
+			return nil, fmt.Errorf("no stats reported for device %s", r.Config.UnifiDevice)
+		}
+
+		return &body.Data[0], nil
+	}
+
+	return nil, fmt.Errorf("stat endpoint not found for device %s", r.Config.UnifiDevice)
+}
+
+// expectedDraw returns the draw in watts a system is expected to add once its
+// port is powered.
+func (r *RedfishServer) expectedDraw(sys RedfishSystem) float64 {
+	if conf, ok := r.Config.Systems[strconv.Itoa(sys.UnifiPort)]; ok && conf.ExpectedDraw > 0 {
+		return conf.ExpectedDraw
+	}
+	if sys.ExpectedDraw > 0 {
+		return sys.ExpectedDraw
+	}
+	return r.Config.PoeExpectedDraw
+}
+
+// poeUsage returns the PoE budget of the switch and its current draw. When
+// the live statistics are unavailable the draw is estimated from the expected
+// draw of every powered system.
+func (r *RedfishServer) poeUsage(ctx context.Context) (budget, draw float64) {
+	stats, err := r.statDevice(ctx)
+	if err == nil {
+		budget = float64(stats.TotalMaxPower)
+		for _, p := range stats.PortTable {
+			draw += float64(p.PoePower)
+		}
+	} else {
+		for _, s := range r.systems() {
+			if s.PoeMode != "off" {
+				draw += r.expectedDraw(s)
+			}
+		}
+	}
+
+	if r.Config.PoeBudget > 0 {
+		budget = r.Config.PoeBudget
+	}
+
+	return
+}
+
+// checkPoeBudget returns an error if powering on the system would exceed the
+// PoE budget of the switch. pending is the expected draw of systems powered
+// on so recently that the switch may not report it yet. Unknown budgets are
+// not enforced.
+func (r *RedfishServer) checkPoeBudget(ctx context.Context, sys RedfishSystem, pending float64) error {
+	budget, draw := r.poeUsage(ctx)
+	if budget <= 0 {
+		return nil
+	}
+	draw += pending
+
+	expected := r.expectedDraw(sys)
+	if draw+expected > budget {
+		return newMessageError(poeBudgetExceededCode,
+			strconv.Itoa(sys.UnifiPort),
+			strconv.FormatFloat(expected, 'f', 1, 64),
+			strconv.FormatFloat(max(budget-draw, 0), 'f', 1, 64),
+			strconv.FormatFloat(budget, 'f', 1, 64),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+type quarantineRequest struct {
+	Reason string `json:"Reason,omitempty"`
+}
+
+// quarantine blocks the client of the system on the controller and, when a
+// quarantine network mode is configured, moves its port to that profile.
+func (r *RedfishServer) quarantine(ctx context.Context, sys RedfishSystem) error {
+	if sys.MacAddress == "" {
+		return fmt.Errorf("no client MAC address known for port %d", sys.UnifiPort)
+	}
+
+	if err := r.client.BlockUserByMAC(ctx, r.Config.UnifiSite, sys.MacAddress); err != nil {
+		return fmt.Errorf("blocking client %s: %w", sys.MacAddress, err)
+	}
+
+	sys.Quarantined = true
+
+	if mode := r.Config.QuarantineNetworkMode; mode != "" && r.state.system(sys.UnifiPort).QuarantineProfileID == "" {
+		previous := sys.PortProfileID
+		if previous == "" {
+			// Remember that the port had no profile so release can clear it.
+			previous = noPortProfile
+		}
+		// The profile is persisted first so a release after a restart
+		// still restores it.
+		if err := r.state.updateSystem(sys.UnifiPort, func(st *systemState) { st.QuarantineProfileID = previous }); err != nil {
+			r.putSystem(sys)
+			return err
+		}
+
+		if err := r.setNetworkMode(ctx, sys, mode); err != nil {
+			r.putSystem(sys)
+			if err := r.state.updateSystem(sys.UnifiPort, func(st *systemState) { st.QuarantineProfileID = "" }); err != nil {
+				log.Printf("state: clearing quarantine profile of system %d: %v", sys.UnifiPort, err)
+			}
+			return fmt.Errorf("moving port %d to network mode %q: %w", sys.UnifiPort, mode, err)
+		}
+		sys, _ = r.system(sys.UnifiPort)
+	}
+
+	r.putSystem(sys)
+
+	return nil
+}
+
+// noPortProfile marks a port that had no profile before being quarantined.
+const noPortProfile = "-"
+
+// release unblocks the client of the system and restores the port profile
+// it used before it was quarantined.
+func (r *RedfishServer) release(ctx context.Context, sys RedfishSystem) error {
+	if sys.MacAddress == "" {
+		return fmt.Errorf("no client MAC address known for port %d", sys.UnifiPort)
+	}
+
+	if err := r.client.UnblockUserByMAC(ctx, r.Config.UnifiSite, sys.MacAddress); err != nil {
+		return fmt.Errorf("unblocking client %s: %w", sys.MacAddress, err)
+	}
+
+	sys.Quarantined = false
+
+	if previous := r.state.system(sys.UnifiPort).QuarantineProfileID; previous != "" {
+		if previous == noPortProfile {
+			previous = ""
+		}
+		if _, err := r.updateDevicePortProfile(ctx, sys.UnifiPort, previous); err != nil {
+			r.putSystem(sys)
+			return fmt.Errorf("restoring port profile of port %d: %w", sys.UnifiPort, err)
+		}
+		sys.PortProfileID = previous
+
+		if err := r.state.updateSystem(sys.UnifiPort, func(st *systemState) { st.QuarantineProfileID = "" }); err != nil {
+			r.putSystem(sys)
+			return err
+		}
+	}
+
+	r.putSystem(sys)
+
+	return nil
+}
+
+// QuarantineSystem blocks a system on the controller.
+func (r *RedfishServer) QuarantineSystem(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	req := quarantineRequest{}
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	err := r.quarantine(c.Request.Context(), sys)
+	user, _ := contextUser(c)
+	r.audit(user, sys, "Quarantine", req.Reason, err)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
+
+// ReleaseSystem lifts the quarantine of a system.
+func (r *RedfishServer) ReleaseSystem(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	req := quarantineRequest{}
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	sys, ok := r.lookupSystem(c, systemId)
+	if !ok {
+		return
+	}
+
+	err := r.release(c.Request.Context(), sys)
+	user, _ := contextUser(c)
+	r.audit(user, sys, "ReleaseQuarantine", req.Reason, err)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
@@ -0,0 +1,585 @@
+package redfish
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The prefixes of the MessageIds of each registry served under
+// /redfish/v1/Registries, made of its RegistryPrefix and the major and minor
+// RegistryVersion.
+const (
+	baseRegistry          = "Base.1.16."
+	resourceEventRegistry = "ResourceEvent.1.3."
+	taskEventRegistry     = "TaskEvent.1.0."
+	unifiRegistry         = "Unifi.1.0."
+)
+
+// registryMessage is a message of a message registry. %1, %2 and so on in
+// Message are replaced by the message arguments, one per ParamTypes.
+type registryMessage struct {
+	Description string
+	Message     string
+	Severity    string
+	ParamTypes  []string
+	Resolution  string
+}
+
+// messageRegistry is a message registry served by this service. The
+// registries published by DMTF only hold the messages this service reports.
+type messageRegistry struct {
+	Prefix       string
+	Version      string
+	Name         string
+	Description  string
+	OwningEntity string
+	Messages     map[string]registryMessage
+}
+
+// id returns the id of the registry, such as Base.1.16.0.
+func (reg *messageRegistry) id() string {
+	return reg.Prefix + "." + reg.Version
+}
+
+var baseMessages = map[string]registryMessage{
+	"Success": {
+		Description: "Indicates that all conditions of a successful operation were met.",
+		Message:     "The request completed successfully.",
+		Severity:    "OK",
+		Resolution:  "None.",
+	},
+	"GeneralError": {
+		Description: "Indicates that a general error has occurred.  Use in @Message.ExtendedInfo is discouraged.  When used in @Message.ExtendedInfo, implementations are expected to include a Resolution property with this message and provide a service-defined resolution to indicate how to resolve the error.",
+		Message:     "A general error has occurred.  See Resolution for information on how to resolve the error, or @Message.ExtendedInfo if Resolution is not provided.",
+		Severity:    "Critical",
+		Resolution:  "None.",
+	},
+	"ActionNotSupported": {
+		Description: "Indicates that the action supplied with the POST operation is not supported by the resource.",
+		Message:     "The action %1 is not supported by the resource.",
+		Severity:    "Critical",
+		ParamTypes:  []string{"string"},
+		Resolution:  "The action supplied cannot be resubmitted to the implementation.  Perhaps the action was invalid, the wrong resource was the target or the implementation documentation may be of assistance.",
+	},
+	"MalformedJSON": {
+		Description: "Indicates that the request body was malformed JSON.",
+		Message:     "The request body submitted was malformed JSON and could not be parsed by the receiving service.",
+		Severity:    "Critical",
+		Resolution:  "Ensure that the request body is valid JSON and resubmit the request.",
+	},
+	"PropertyValueTypeError": {
+		Description: "Indicates that a property was given the wrong value type, such as when a number is supplied for a property that requires a string.",
+		Message:     "The value '%1' for the property %2 is not a type that the property can accept.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Correct the value for the property in the request body and resubmit the request if the operation failed.",
+	},
+	"PropertyValueFormatError": {
+		Description: "Indicates that a property was given the correct value type but the value of that property was not supported.",
+		Message:     "The value '%1' for the property %2 is not a format that the property can accept.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Correct the value for the property in the request body and resubmit the request if the operation failed.",
+	},
+	"PropertyValueNotInList": {
+		Description: "Indicates that a property was given the correct value type but the value of that property was not supported.  The value is not in an enumeration.",
+		Message:     "The value '%1' for the property %2 is not in the list of acceptable values.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Choose a value from the enumeration list that the implementation can support and resubmit the request if the operation failed.",
+	},
+	"PropertyNotWritable": {
+		Description: "Indicates that a property was given a value in the request body, but the property is a readonly property.",
+		Message:     "The property %1 is a read-only property and cannot be assigned a value.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string"},
+		Resolution:  "Remove the property from the request body and resubmit the request if the operation failed.",
+	},
+	"PropertyMissing": {
+		Description: "Indicates that a required property was not supplied as part of the request.",
+		Message:     "The property %1 is a required property and must be included in the request.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string"},
+		Resolution:  "Ensure that the property is in the request body and has a valid value and resubmit the request if the operation failed.",
+	},
+	"PropertyValueOutOfRange": {
+		Description: "Indicates that a property was given the correct value type but the value of that property is outside the supported range.",
+		Message:     "The value '%1' for the property %2 is not in the supported range of acceptable values.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Correct the value for the property in the request body and resubmit the request if the operation failed.",
+	},
+	"PropertyValueError": {
+		Description: "Indicates that a property was given an invalid value.",
+		Message:     "The value provided for the property %1 is not valid.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string"},
+		Resolution:  "Correct the value for the property in the request body and resubmit the request if the operation failed.",
+	},
+	"PropertyUnknown": {
+		Description: "Indicates that an unknown property was included in the request body.",
+		Message:     "The property %1 is not in the list of valid properties for the resource.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string"},
+		Resolution:  "Remove the unknown property from the request body and resubmit the request if the operation failed.",
+	},
+	"ArraySizeTooLong": {
+		Description: "Indicates that the size of the array exceeded the maximum number of elements.",
+		Message:     "The array provided for property %1 exceeds the size limit %2.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "number"},
+		Resolution:  "Resubmit the request with an appropriate array size.",
+	},
+	"ActionParameterMissing": {
+		Description: "Indicates that the action requested was missing an action parameter that is required to process the action.",
+		Message:     "The action %1 requires the parameter %2 to be present in the request body.",
+		Severity:    "Critical",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Supply the action with the required parameter in the request body when the request is resubmitted.",
+	},
+	"ActionParameterNotSupported": {
+		Description: "Indicates that the parameter supplied for the action is not supported on the resource.",
+		Message:     "The parameter %1 for the action %2 is not supported on the target resource.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Remove the parameter supplied and resubmit the request if the operation failed.",
+	},
+	"ActionParameterValueNotInList": {
+		Description: "Indicates that a parameter was given the correct value type but the value of that parameter was not supported.  The value is not in an enumeration.",
+		Message:     "The value '%1' for the parameter %2 in the action %3 is not in the list of acceptable values.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string", "string"},
+		Resolution:  "Choose a value from the enumeration list that the implementation can support and resubmit the request if the operation failed.",
+	},
+	"ActionParameterValueFormatError": {
+		Description: "Indicates that a parameter was given the correct value type but the value of that parameter was not supported.  This includes the value size or length has been exceeded.",
+		Message:     "The value '%1' for the parameter %2 in the action %3 is not a format that the parameter can accept.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string", "string"},
+		Resolution:  "Correct the value for the parameter in the request body and resubmit the request if the operation failed.",
+	},
+	"ActionParameterValueError": {
+		Description: "Indicates that a parameter was given an invalid value.",
+		Message:     "The value for the parameter %1 in the action %2 is invalid.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Correct the value for the parameter in the request body and resubmit the request if the operation failed.",
+	},
+	"QueryParameterValueFormatError": {
+		Description: "Indicates that a query parameter was given the correct value type but the value of that parameter was not supported.",
+		Message:     "The value '%1' for the parameter %2 is not a format that the parameter can accept.",
+		Severity:    "Warning",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Correct the value for the query parameter in the request and resubmit the request if the operation failed.",
+	},
+	"ResourceNotFound": {
+		Description: "Indicates that the operation expected a resource identifier that corresponds to an existing resource but one was not found.",
+		Message:     "The requested resource of type %1 named '%2' was not found.",
+		Severity:    "Critical",
+		ParamTypes:  []string{"string", "string"},
+		Resolution:  "Provide a valid resource identifier and resubmit the request.",
+	},
+	"ResourceAlreadyExists": {
+		Description: "Indicates that a resource change or creation was attempted but that the operation cannot proceed because the resource already exists.",
+		Message:     "The requested resource of type %1 with the property %2 with the value '%3' already exists.",
+		Severity:    "Critical",
+		ParamTypes:  []string{"string", "string", "string"},
+		Resolution:  "Do not repeat the create operation as the resource has already been created.",
+	},
+	"ResourceInUse": {
+		Description: "Indicates that a change was requested to a resource but the change was rejected due to the resource being in use or transition.",
+		Message:     "The change to the requested resource failed because the resource is in use or in transition.",
+		Severity:    "Warning",
+		Resolution:  "Remove the condition and resubmit the request if the operation failed.",
+	},
+	"OperationNotAllowed": {
+		Description: "Indicates that the HTTP method in the request is not allowed on this resource.",
+		Message:     "The HTTP method is not allowed on this resource.",
+		Severity:    "Critical",
+		Resolution:  "None.",
+	},
+	"InsufficientPrivilege": {
+		Description: "Indicates that the credentials associated with the established session do not have sufficient privileges for the requested operation.",
+		Message:     "There are insufficient privileges for the account or credentials associated with the current session to perform the requested operation.",
+		Severity:    "Critical",
+		Resolution:  "Either abandon the operation or change the associated access rights and resubmit the request if the operation failed.",
+	},
+	"NoValidSession": {
+		Description: "Indicates that the operation failed because a valid session is required in order to access any resources.",
+		Message:     "There is no valid session established with the implementation.",
+		Severity:    "Critical",
+		Resolution:  "Establish a session before attempting any operations.",
+	},
+}
+
+var registries = []*messageRegistry{
+	{
+		Prefix:       "Base",
+		Version:      "1.16.0",
+		Name:         "Base Message Registry",
+		Description:  "This registry defines the base messages for Redfish.",
+		OwningEntity: "DMTF",
+		Messages:     baseMessages,
+	},
+	{
+		Prefix:       "ResourceEvent",
+		Version:      "1.3.0",
+		Name:         "Resource Event Message Registry",
+		Description:  "This registry defines the messages to use for resource events.",
+		OwningEntity: "DMTF",
+		Messages: map[string]registryMessage{
+			"ResourceChanged": {
+				Description: "Indicates that one or more resource properties have changed.  This is not used whenever there is another event message for that specific change, such as only the state has changed.",
+				Message:     "One or more resource properties have changed.",
+				Severity:    "OK",
+				Resolution:  "None.",
+			},
+			"ResourceStateChanged": {
+				Description: "Indicates that the state of a resource has changed.",
+				Message:     "The state of resource '%1' has changed to %2.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string", "string"},
+				Resolution:  "None.",
+			},
+			"ResourceStatusChangedOK": {
+				Description: "Indicates that the health of a resource has changed to OK.",
+				Message:     "The health of resource '%1' has changed to %2.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string", "string"},
+				Resolution:  "None.",
+			},
+			"TestMessage": {
+				Description: "A test message used to validate event delivery mechanisms.",
+				Message:     "Test message.",
+				Severity:    "OK",
+				Resolution:  "None.",
+			},
+		},
+	},
+	{
+		Prefix:       "TaskEvent",
+		Version:      "1.0.3",
+		Name:         "Task Event Message Registry",
+		Description:  "This registry defines the messages for task related events.",
+		OwningEntity: "DMTF",
+		Messages: map[string]registryMessage{
+			"TaskStarted": {
+				Description: "The task with the specified identifier has started.",
+				Message:     "The task with Id '%1' has started.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string"},
+				Resolution:  "None.",
+			},
+			"TaskProgressChanged": {
+				Description: "The task with the specified identifier has changed the progress.",
+				Message:     "The task with Id '%1' has changed to progress %2 percent complete.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string", "number"},
+				Resolution:  "None.",
+			},
+			"TaskCompletedOK": {
+				Description: "The task with the specified identifier has completed.",
+				Message:     "The task with Id '%1' has completed.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string"},
+				Resolution:  "None.",
+			},
+			"TaskCompletedWarning": {
+				Description: "The task with the specified identifier has completed with warnings.",
+				Message:     "The task with Id '%1' has completed with warnings.",
+				Severity:    "Warning",
+				ParamTypes:  []string{"string"},
+				Resolution:  "None.",
+			},
+			"TaskAborted": {
+				Description: "The task with the specified identifier has been aborted.",
+				Message:     "The task with Id '%1' has been aborted.",
+				Severity:    "Critical",
+				ParamTypes:  []string{"string"},
+				Resolution:  "None.",
+			},
+		},
+	},
+	{
+		Prefix:       "Unifi",
+		Version:      "1.0.0",
+		Name:         "UniFi Message Registry",
+		Description:  "This registry defines the messages of systems powered and booted through UniFi switches.",
+		OwningEntity: "go-redfish-uefi",
+		Messages: map[string]registryMessage{
+			"ControllerUnreachable": {
+				Description: "Indicates that the UniFi controller managing the switch could not be reached.",
+				Message:     "The UniFi controller at %1 cannot be reached: %2",
+				Severity:    "Critical",
+				ParamTypes:  []string{"string", "string"},
+				Resolution:  "Check that the controller is running and reachable from this service and that the configured credentials are valid.",
+			},
+			"PoEBudgetExceeded": {
+				Description: "Indicates that powering on a system was refused because it would exceed the PoE budget of its switch.",
+				Message:     "Powering on port %1 needs %2 W but only %3 W of the %4 W PoE budget is available.",
+				Severity:    "Warning",
+				ParamTypes:  []string{"number", "number", "number", "number"},
+				Resolution:  "Power off other systems of the switch, or set Oem.Unifi.PoEBudgetOverride in the request to power on regardless.",
+			},
+			"PortNotFound": {
+				Description: "Indicates that the switch port of a system does not exist on its switch.",
+				Message:     "Port %1 was not found on switch %2.",
+				Severity:    "Critical",
+				ParamTypes:  []string{"number", "string"},
+				Resolution:  "Correct the port of the system in the configuration, or check that the configured switch is the one it is connected to.",
+			},
+			"SystemQuarantined": {
+				Description: "Indicates that a request was refused because the system is quarantined.",
+				Message:     "System %1 is quarantined, its network mode cannot be changed.",
+				Severity:    "Warning",
+				ParamTypes:  []string{"string"},
+				Resolution:  "Release the system with Unifi.ReleaseQuarantine and resubmit the request.",
+			},
+			"SystemReset": {
+				Description: "Indicates that a system was reset by a task.",
+				Message:     "System %1 was reset.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string"},
+				Resolution:  "None.",
+			},
+			"SystemResetFailed": {
+				Description: "Indicates that a task failed to reset a system.",
+				Message:     "System %1 was not reset: %2",
+				Severity:    "Warning",
+				ParamTypes:  []string{"string", "string"},
+				Resolution:  "Resolve the reported error and reset the system again.",
+			},
+			"FirmwareUpdated": {
+				Description: "Indicates that a firmware image was installed, carrying the variables of the image it replaced across.",
+				Message:     "The firmware image %1 was updated, %2 variables migrated.",
+				Severity:    "OK",
+				ParamTypes:  []string{"string", "number"},
+				Resolution:  "None.",
+			},
+			"FirmwareUpdateFailed": {
+				Description: "Indicates that a firmware image could not be installed.",
+				Message:     "The firmware image %1 was not updated: %2",
+				Severity:    "Warning",
+				ParamTypes:  []string{"string", "string"},
+				Resolution:  "Resolve the reported error and update the firmware again.",
+			},
+		},
+	},
+}
+
+// formatMessage replaces the placeholders of a registry message by args.
+func formatMessage(message string, args []string) string {
+	// Replaced from the last argument so %1 does not match %10.
+	for i := len(args); i > 0; i-- {
+		message = strings.ReplaceAll(message, fmt.Sprintf("%%%d", i), args[i-1])
+	}
+	return message
+}
+
+// lookupMessage returns the registry message of a MessageId. Any version of
+// a registry with the same major version matches.
+func lookupMessage(messageId string) (registryMessage, bool) {
+	parts := strings.Split(messageId, ".")
+	if len(parts) != 4 {
+		return registryMessage{}, false
+	}
+
+	for _, reg := range registries {
+		if reg.Prefix == parts[0] && strings.HasPrefix(reg.Version, parts[1]+".") {
+			m, ok := reg.Messages[parts[3]]
+			return m, ok
+		}
+	}
+
+	return registryMessage{}, false
+}
+
+// messageText returns the text of a registry message formatted from args.
+func messageText(messageId string, args ...string) string {
+	m, _ := lookupMessage(messageId)
+	return formatMessage(m.Message, args)
+}
+
+// messageError is an error reported with a registry message.
+type messageError struct {
+	messageId string
+	args      []string
+	// related are the JSON pointers of the request body properties at fault.
+	related []string
+	err     error
+}
+
+func (e *messageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *messageError) Unwrap() error {
+	return e.err
+}
+
+// newMessageError returns an error reported with a registry message, its
+// text formatted from args.
+func newMessageError(messageId string, args ...string) error {
+	return &messageError{
+		messageId: messageId,
+		args:      args,
+		err:       fmt.Errorf("%s", messageText(messageId, args...)),
+	}
+}
+
+// propertyError returns an error reported with the Base message name and its
+// args, relating to the property at the JSON pointer path of the request
+// body.
+func propertyError(path, name string, args ...string) error {
+	return &messageError{
+		messageId: baseRegistry + name,
+		args:      args,
+		related:   []string{"#/" + path},
+		err:       fmt.Errorf("%s", messageText(baseRegistry+name, args...)),
+	}
+}
+
+func controllerUnreachable(endpoint string, err error) error {
+	return &messageError{
+		messageId: unifiRegistry + "ControllerUnreachable",
+		args:      []string{endpoint, err.Error()},
+		err:       err,
+	}
+}
+
+// messageRegistryFile is a MessageRegistryFile resource locating a registry.
+type messageRegistryFile struct {
+	OdataId     string             `json:"@odata.id"`
+	OdataType   string             `json:"@odata.type"`
+	Id          string             `json:"Id"`
+	Name        string             `json:"Name"`
+	Description string             `json:"Description"`
+	Languages   []string           `json:"Languages"`
+	Registry    string             `json:"Registry"`
+	Location    []registryLocation `json:"Location"`
+}
+
+type registryLocation struct {
+	Language string `json:"Language"`
+	Uri      string `json:"Uri"`
+}
+
+// messageRegistryResource is a MessageRegistry resource.
+type messageRegistryResource struct {
+	OdataId         string                             `json:"@odata.id"`
+	OdataType       string                             `json:"@odata.type"`
+	Id              string                             `json:"Id"`
+	Name            string                             `json:"Name"`
+	Description     string                             `json:"Description"`
+	Language        string                             `json:"Language"`
+	OwningEntity    string                             `json:"OwningEntity"`
+	RegistryPrefix  string                             `json:"RegistryPrefix"`
+	RegistryVersion string                             `json:"RegistryVersion"`
+	Messages        map[string]registryMessageResource `json:"Messages"`
+}
+
+type registryMessageResource struct {
+	Description     string   `json:"Description"`
+	Message         string   `json:"Message"`
+	MessageSeverity string   `json:"MessageSeverity"`
+	NumberOfArgs    int      `json:"NumberOfArgs"`
+	ParamTypes      []string `json:"ParamTypes,omitempty"`
+	Resolution      string   `json:"Resolution"`
+}
+
+func registryPath(reg *messageRegistry) string {
+	return "/redfish/v1/Registries/" + reg.Prefix
+}
+
+// lookupRegistry returns the registry of the request, writing an error
+// response if it cannot be found.
+func lookupRegistry(c *gin.Context) (*messageRegistry, bool) {
+	id := c.Param("RegistryId")
+	for _, reg := range registries {
+		if reg.Prefix == id {
+			return reg, true
+		}
+	}
+
+	resourceNotFound(c, "MessageRegistryFile", id)
+	return nil, false
+}
+
+// ListRegistries lists the message registries of the MessageIds reported by
+// this service.
+func (r *RedfishServer) ListRegistries(c *gin.Context) {
+	members := make([]IdRef, 0, len(registries))
+	for _, reg := range registries {
+		members = append(members, IdRef{OdataId: ptr(registryPath(reg))})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &members,
+		OdataContext:      ptr("/redfish/v1/$metadata#MessageRegistryFileCollection.MessageRegistryFileCollection"),
+		OdataType:         "#MessageRegistryFileCollection.MessageRegistryFileCollection",
+		Name:              ptr("Registry File Collection"),
+		OdataId:           "/redfish/v1/Registries",
+		MembersOdataCount: ptr(len(members)),
+	})
+}
+
+// GetRegistryFile locates a message registry.
+func (r *RedfishServer) GetRegistryFile(c *gin.Context) {
+	reg, ok := lookupRegistry(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, &messageRegistryFile{
+		OdataId:     registryPath(reg),
+		OdataType:   "#MessageRegistryFile.v1_1_3.MessageRegistryFile",
+		Id:          reg.Prefix,
+		Name:        reg.Name + " File",
+		Description: reg.Description,
+		Languages:   []string{"en"},
+		Registry:    reg.id(),
+		Location: []registryLocation{{
+			Language: "en",
+			Uri:      registryPath(reg) + "/" + reg.Prefix,
+		}},
+	})
+}
+
+// GetRegistry serves a message registry.
+func (r *RedfishServer) GetRegistry(c *gin.Context) {
+	reg, ok := lookupRegistry(c)
+	if !ok {
+		return
+	}
+	if c.Param("RegistryFile") != reg.Prefix {
+		resourceNotFound(c, "MessageRegistry", c.Param("RegistryFile"))
+		return
+	}
+
+	messages := make(map[string]registryMessageResource, len(reg.Messages))
+	for name, m := range reg.Messages {
+		messages[name] = registryMessageResource{
+			Description:     m.Description,
+			Message:         m.Message,
+			MessageSeverity: m.Severity,
+			NumberOfArgs:    len(m.ParamTypes),
+			ParamTypes:      m.ParamTypes,
+			Resolution:      m.Resolution,
+		}
+	}
+
+	c.JSON(200, &messageRegistryResource{
+		OdataId:         registryPath(reg) + "/" + reg.Prefix,
+		OdataType:       "#MessageRegistry.v1_6_0.MessageRegistry",
+		Id:              reg.id(),
+		Name:            reg.Name,
+		Description:     reg.Description,
+		Language:        "en",
+		OwningEntity:    reg.OwningEntity,
+		RegistryPrefix:  reg.Prefix,
+		RegistryVersion: reg.Version,
+		Messages:        messages,
+	})
+}
@@ -0,0 +1,442 @@
+package redfish
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appkins-org/go-redfish-uefi/pkg/firmware"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultFirmwareUploadMaxSize = 64 << 20
+
+	repositoryInventoryPrefix = "/redfish/v1/UpdateService/FirmwareInventory/Repository-"
+)
+
+// errInvalidFirmware is returned for uploads that hold no RPi UEFI image.
+var errInvalidFirmware = errors.New("not an RPi UEFI firmware image")
+
+// repositoryImage is a firmware image staged in the repository.
+type repositoryImage struct {
+	Id         string    `json:"id"`
+	Filename   string    `json:"filename"`
+	Sha256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	Version    string    `json:"version,omitempty"`
+	Uploaded   time.Time `json:"uploaded"`
+	UploadedBy string    `json:"uploaded_by,omitempty"`
+
+	// BundleSha256 is the checksum of the file uploaded, which differs from
+	// Sha256 when the image was extracted from a release zip.
+	BundleSha256 string `json:"bundle_sha256"`
+}
+
+// firmwareRepository stages uploaded firmware images in a directory, indexed
+// by a JSON file next to them. An empty directory disables uploads.
+type firmwareRepository struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+
+	Images map[string]*repositoryImage `json:"images"`
+}
+
+func (s *firmwareRepository) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+func loadFirmwareRepository(dir string, maxSize int64) (*firmwareRepository, error) {
+	if maxSize <= 0 {
+		maxSize = defaultFirmwareUploadMaxSize
+	}
+
+	s := &firmwareRepository{
+		dir:     dir,
+		maxSize: maxSize,
+		Images:  make(map[string]*repositoryImage),
+	}
+
+	if dir == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(s.indexPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+
+	if s.Images == nil {
+		s.Images = make(map[string]*repositoryImage)
+	}
+
+	return s, nil
+}
+
+func (s *firmwareRepository) enabled() bool {
+	return s.dir != ""
+}
+
+// save writes the index to a temporary file and renames it over the previous
+// one. The caller must hold mu.
+func (s *firmwareRepository) save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.indexPath())
+}
+
+func (s *firmwareRepository) path(id string) string {
+	return filepath.Join(s.dir, id+".fd")
+}
+
+func (s *firmwareRepository) get(id string) (repositoryImage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	img, ok := s.Images[id]
+	if !ok {
+		return repositoryImage{}, false
+	}
+	return *img, true
+}
+
+// list returns the staged images, oldest first.
+func (s *firmwareRepository) list() []repositoryImage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	images := make([]repositoryImage, 0, len(s.Images))
+	for _, id := range slices.Sorted(maps.Keys(s.Images)) {
+		images = append(images, *s.Images[id])
+	}
+	slices.SortStableFunc(images, func(a, b repositoryImage) int {
+		return a.Uploaded.Compare(b.Uploaded)
+	})
+	return images
+}
+
+func (s *firmwareRepository) delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.Images[id]; !ok {
+		return false, nil
+	}
+
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return true, err
+	}
+	delete(s.Images, id)
+
+	return true, s.save()
+}
+
+// extractFirmwareImage extracts RPI_EFI.fd of an upstream RPi4 UEFI release
+// zip to a temporary file in dir.
+func extractFirmwareImage(zipPath, dir string) (string, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidFirmware, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if path.Base(f.Name) != firmwareImage {
+			continue
+		}
+		if f.UncompressedSize64 > maxFirmwareImageSize {
+			return "", errImageTooLarge
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+
+		out, err := os.CreateTemp(dir, "extract-*")
+		if err != nil {
+			return "", err
+		}
+		defer out.Close()
+
+		// The size in the zip header is not trusted.
+		n, err := io.Copy(out, io.LimitReader(rc, maxFirmwareImageSize+1))
+		if err == nil && n > maxFirmwareImageSize {
+			err = errImageTooLarge
+		}
+		if err != nil {
+			os.Remove(out.Name())
+			return "", err
+		}
+		return out.Name(), nil
+	}
+
+	return "", fmt.Errorf("%w: %s not found in zip", errInvalidFirmware, firmwareImage)
+}
+
+// stage streams an uploaded firmware bundle, a single image or a release
+// zip, to disk and adds the image it holds to the repository. An image
+// uploaded before is returned as it was staged.
+func (s *firmwareRepository) stage(r io.Reader, filename, user string) (repositoryImage, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return repositoryImage{}, err
+	}
+
+	upload, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return repositoryImage{}, err
+	}
+	defer os.Remove(upload.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(upload, h), r)
+	if cerr := upload.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return repositoryImage{}, err
+	}
+	bundleSum := hex.EncodeToString(h.Sum(nil))
+
+	imagePath := upload.Name()
+	if isZip(imagePath) {
+		if imagePath, err = extractFirmwareImage(upload.Name(), s.dir); err != nil {
+			return repositoryImage{}, err
+		}
+		defer os.Remove(imagePath)
+	}
+
+	fi, err := os.Stat(imagePath)
+	if err != nil {
+		return repositoryImage{}, err
+	}
+	if fi.Size() > maxFirmwareImageSize {
+		return repositoryImage{}, errImageTooLarge
+	}
+
+	b, err := os.ReadFile(imagePath)
+	if err != nil {
+		return repositoryImage{}, err
+	}
+	if _, err := firmware.Parse(b); err != nil {
+		return repositoryImage{}, fmt.Errorf("%w: %v", errInvalidFirmware, err)
+	}
+
+	sum := sha256.Sum256(b)
+	img := &repositoryImage{
+		Id:           hex.EncodeToString(sum[:6]),
+		Filename:     path.Base(filename),
+		Sha256:       hex.EncodeToString(sum[:]),
+		Size:         int64(len(b)),
+		Uploaded:     time.Now().UTC(),
+		UploadedBy:   user,
+		BundleSha256: bundleSum,
+	}
+	if v, ok := firmware.UefiVersion(b); ok {
+		img.Version = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.Images[img.Id]; ok {
+		return *cur, nil
+	}
+
+	if err := os.Rename(imagePath, s.path(img.Id)); err != nil {
+		return repositoryImage{}, err
+	}
+	s.Images[img.Id] = img
+
+	return *img, s.save()
+}
+
+// isZip reports whether the file at path is a zip archive.
+func isZip(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, []byte("PK\x03\x04"))
+}
+
+func repositoryItem(img repositoryImage, path string) softwareItem {
+	item := softwareItem{
+		Id:          "Repository-" + img.Id,
+		Artifact:    bootArtifacts[0],
+		Description: img.Filename,
+		Path:        path,
+		Sha256:      img.Sha256,
+		Modified:    img.Uploaded,
+		Version:     img.Version,
+	}
+	if item.Version == "" {
+		item.Version = "sha256:" + img.Sha256[:16]
+	}
+	return item
+}
+
+// updateParameters is the UpdateParameters part of a multipart upload.
+type updateParameters struct {
+	Targets *[]string `json:"Targets,omitempty"`
+}
+
+// FirmwareInventoryDownloadImage implements ServerInterface. It serves as
+// both HttpPushUri and MultipartHttpPushUri: the uploaded bundle is staged
+// in the firmware repository and, when a multipart upload carries
+// UpdateParameters, installed to its Targets like SimpleUpdate.
+func (r *RedfishServer) FirmwareInventoryDownloadImage(c *gin.Context) {
+	if !r.repository.enabled() {
+		notImplemented(c)
+		return
+	}
+
+	user, _ := contextUser(c)
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, r.repository.maxSize)
+
+	var img repositoryImage
+	var params *updateParameters
+	var err error
+
+	mediaType, mediaParams, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		staged := false
+		mr := multipart.NewReader(body, mediaParams["boundary"])
+		for {
+			part, perr := mr.NextPart()
+			if perr == io.EOF {
+				break
+			} else if perr != nil {
+				err = perr
+				break
+			}
+
+			switch part.FormName() {
+			case "UpdateParameters":
+				params = &updateParameters{}
+				if json.NewDecoder(part).Decode(params) != nil {
+					err = newMessageError(baseRegistry + "MalformedJSON")
+				}
+			case "UpdateFile":
+				img, err = r.repository.stage(part, part.FileName(), user)
+				staged = err == nil
+			}
+			part.Close()
+
+			if err != nil {
+				break
+			}
+		}
+		if err == nil && !staged {
+			propertyMissing(c, "UpdateFile")
+			return
+		}
+	} else {
+		img, err = r.repository.stage(body, "upload.fd", user)
+	}
+
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes):
+		c.JSON(413, redfishError(fmt.Errorf("upload exceeds %d bytes", r.repository.maxSize)))
+		return
+	case errors.Is(err, errInvalidFirmware), errors.Is(err, errImageTooLarge):
+		invalidProperty(c, "UpdateFile", "PropertyValueError", "UpdateFile")
+		return
+	case isMessage(err, baseRegistry+"MalformedJSON"):
+		c.JSON(400, redfishError(err))
+		return
+	case err != nil:
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	location := repositoryInventoryPrefix + img.Id
+
+	if params == nil {
+		c.Header("Location", location)
+		c.JSON(201, softwareInventoryFor(r.Config.TftpRootDirectory, repositoryItem(img, r.repository.path(img.Id))))
+		return
+	}
+
+	if err := r.refreshSystems(c.Request.Context()); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	shared, systems, err := r.updateTargets(params.Targets, func(target string) error {
+		return propertyError("Targets", "PropertyValueNotInList", target, "Targets")
+	})
+	if err != nil {
+		c.JSON(400, redfishError(err))
+		return
+	}
+
+	t := r.startFirmwareUpdate(user, location, "/redfish/v1/UpdateService/FirmwareInventory", func(context.Context) ([]byte, error) {
+		return os.ReadFile(r.repository.path(img.Id))
+	}, shared, systems)
+
+	acceptTask(c, t)
+}
+
+// DeleteSoftwareInventory deletes an image staged in the firmware
+// repository. Boot files of the TFTP root cannot be deleted.
+func (r *RedfishServer) DeleteSoftwareInventory(c *gin.Context) {
+	id, ok := strings.CutPrefix(c.Param("softwareId"), "Repository-")
+	if !ok {
+		// Only staged images can be deleted.
+		methodNotAllowed(c, "GET")
+		return
+	}
+
+	found, err := r.repository.delete(id)
+	if !found {
+		resourceNotFound(c, "SoftwareInventory", c.Param("softwareId"))
+		return
+	}
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
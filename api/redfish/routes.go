@@ -0,0 +1,75 @@
+package redfish
+
+import "github.com/gin-gonic/gin"
+
+// RegisterExtendedHandlers registers the routes served by RedfishServer that
+// are not part of the generated ServerInterface. Path parameters reuse the
+// generated names so both sets of routes can share the router tree.
+func RegisterExtendedHandlers(router gin.IRouter, r *RedfishServer) {
+	router.GET("/health", r.Health)
+	router.GET("/redfish/v1/AccountService", r.GetAccountService)
+	router.GET("/redfish/v1/AccountService/Accounts", r.ListAccounts)
+	router.POST("/redfish/v1/AccountService/Accounts", r.CreateAccount)
+	router.GET("/redfish/v1/AccountService/Accounts/:AccountId", r.GetAccount)
+	router.PATCH("/redfish/v1/AccountService/Accounts/:AccountId", r.SetAccount)
+	router.DELETE("/redfish/v1/AccountService/Accounts/:AccountId", r.DeleteAccount)
+	router.GET("/redfish/v1/AccountService/Roles", r.ListRoles)
+	router.GET("/redfish/v1/AccountService/Roles/:RoleId", r.GetRole)
+	router.GET("/redfish/v1/CertificateService", r.GetCertificateService)
+	router.GET("/redfish/v1/CertificateService/CertificateLocations", r.GetCertificateLocations)
+	router.POST("/redfish/v1/CertificateService/Actions/CertificateService.ReplaceCertificate", r.ReplaceCertificate)
+	router.GET("/redfish/v1/EventService", r.GetEventService)
+	router.PATCH("/redfish/v1/EventService", r.SetEventService)
+	router.POST("/redfish/v1/EventService/Actions/EventService.SubmitTestEvent", r.SubmitTestEvent)
+	router.GET("/redfish/v1/EventService/SSE", r.StreamEvents)
+	router.GET("/redfish/v1/EventService/Subscriptions", r.ListSubscriptions)
+	router.POST("/redfish/v1/EventService/Subscriptions", r.CreateSubscription)
+	router.GET("/redfish/v1/EventService/Subscriptions/:SubscriptionId", r.GetSubscription)
+	router.DELETE("/redfish/v1/EventService/Subscriptions/:SubscriptionId", r.DeleteSubscription)
+	router.GET("/redfish/v1/Managers/:managerId/NetworkProtocol", r.GetManagerNetworkProtocol)
+	router.GET("/redfish/v1/Managers/:managerId/NetworkProtocol/HTTPS/Certificates", r.ListHttpsCertificates)
+	router.GET("/redfish/v1/Managers/:managerId/NetworkProtocol/HTTPS/Certificates/:CertificateId", r.GetHttpsCertificate)
+	router.GET("/redfish/v1/Registries", r.ListRegistries)
+	router.GET("/redfish/v1/Registries/:RegistryId", r.GetRegistryFile)
+	router.GET("/redfish/v1/Registries/:RegistryId/:RegistryFile", r.GetRegistry)
+	router.GET("/redfish/v1/SessionService", r.GetSessionService)
+	router.PATCH("/redfish/v1/SessionService", r.SetSessionService)
+	router.GET("/redfish/v1/SessionService/Sessions", r.ListSessions)
+	router.POST("/redfish/v1/SessionService/Sessions", r.CreateSession)
+	router.GET("/redfish/v1/SessionService/Sessions/:SessionId", r.GetSession)
+	router.DELETE("/redfish/v1/SessionService/Sessions/:SessionId", r.DeleteSession)
+	router.GET("/redfish/v1/TaskService", r.GetTaskService)
+	router.GET("/redfish/v1/TaskService/TaskMonitors/:taskId", r.GetTaskMonitor)
+	router.GET("/redfish/v1/Oem/Unifi/DhcpBoot", r.GetDhcpBoot)
+	router.POST("/redfish/v1/Oem/Unifi/DhcpBoot/Actions/Unifi.Reconcile", r.ReconcileDhcpBoot)
+	router.POST("/redfish/v1/Systems/Actions/Oem/Unifi.Reset", r.ResetSystems)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/Actions/Oem/Unifi.Quarantine", r.QuarantineSystem)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/Actions/Oem/Unifi.ReleaseQuarantine", r.ReleaseSystem)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/Actions/Oem/Unifi.SetNetworkMode", r.SetNetworkMode)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/Bios", r.GetBios)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/Bios/Settings", r.GetBiosSettings)
+	router.PATCH("/redfish/v1/Systems/:ComputerSystemId/Bios/Settings", r.SetBiosSettings)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot", r.GetSecureBoot)
+	router.PATCH("/redfish/v1/Systems/:ComputerSystemId/SecureBoot", r.SetSecureBoot)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/Actions/SecureBoot.ResetKeys", r.ResetSecureBootKeys)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases", r.ListSecureBootDatabases)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId", r.GetSecureBootDatabase)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Actions/SecureBootDatabase.ResetKeys", r.ResetSecureBootDatabaseKeys)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Certificates", r.ListSecureBootCertificates)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Certificates", r.EnrollSecureBootCertificate)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Certificates/:CertificateId", r.GetSecureBootCertificate)
+	router.DELETE("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Certificates/:CertificateId", r.DeleteSecureBootCertificate)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Signatures", r.ListSecureBootSignatures)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Signatures", r.EnrollSecureBootSignature)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Signatures/:SignatureId", r.GetSecureBootSignature)
+	router.DELETE("/redfish/v1/Systems/:ComputerSystemId/SecureBoot/SecureBootDatabases/:DatabaseId/Signatures/:SignatureId", r.DeleteSecureBootSignature)
+	router.DELETE("/redfish/v1/UpdateService/FirmwareInventory/:softwareId", r.DeleteSoftwareInventory)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/LogServices", r.ListLogServices)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/LogServices/EventLog", r.GetEventLogService)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/LogServices/EventLog/Entries", r.ListEventLogEntries)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/LogServices/EventLog/Entries/:LogEntryId", r.GetEventLogEntry)
+	router.POST("/redfish/v1/Systems/:ComputerSystemId/LogServices/EventLog/Actions/LogService.ClearLog", r.ClearEventLog)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/EthernetInterfaces", r.ListEthernetInterfaces)
+	router.GET("/redfish/v1/Systems/:ComputerSystemId/EthernetInterfaces/:EthernetInterfaceId", r.GetEthernetInterface)
+	router.PATCH("/redfish/v1/Systems/:ComputerSystemId/EthernetInterfaces/:EthernetInterfaceId", r.SetEthernetInterface)
+}
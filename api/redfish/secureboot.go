@@ -0,0 +1,790 @@
+package redfish
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+	"github.com/0x5a17ed/uefi/efi/efivario"
+	"github.com/appkins-org/go-redfish-uefi/pkg/firmware"
+	"github.com/gin-gonic/gin"
+)
+
+// secureBootEnableGuid is the vendor GUID of the SecureBootEnable variable
+// EDK2 uses to turn Secure Boot on and off.
+var secureBootEnableGuid = efiguid.MustFromString("f0a30bc7-af08-4556-99c4-001009c93a44")
+
+// signatureOwner owns the keys enrolled through the API unless a request
+// names another owner.
+var signatureOwner = efiguid.MustFromString("6c3b2f5e-2a7d-4f4e-9a1b-5e8d0c7f3a21")
+
+// Types of Secure Boot key resets.
+const (
+	resetAllKeysToDefault = "ResetAllKeysToDefault"
+	deleteAllKeys         = "DeleteAllKeys"
+	deletePK              = "DeletePK"
+)
+
+// secureBootDatabase describes a Secure Boot key database variable.
+type secureBootDatabase struct {
+	Id   string
+	Guid efiguid.GUID
+}
+
+var secureBootDatabases = []secureBootDatabase{
+	{Id: "PK", Guid: firmware.GlobalVariableGuid},
+	{Id: "KEK", Guid: firmware.GlobalVariableGuid},
+	{Id: "db", Guid: firmware.ImageSecurityDatabaseGuid},
+	{Id: "dbx", Guid: firmware.ImageSecurityDatabaseGuid},
+}
+
+func findSecureBootDatabase(id string) (secureBootDatabase, bool) {
+	for _, db := range secureBootDatabases {
+		if db.Id == id {
+			return db, true
+		}
+	}
+	return secureBootDatabase{}, false
+}
+
+// signatures returns the signatures of the given type stored in a database.
+func (db secureBootDatabase) signatures(img *firmware.Image, sigType efiguid.GUID) ([]firmware.Signature, error) {
+	v, ok := img.Get(db.Id, db.Guid)
+	if !ok {
+		return nil, nil
+	}
+
+	lists, err := firmware.ParseSignatureLists(v.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", db.Id, err)
+	}
+
+	var sigs []firmware.Signature
+	for _, l := range lists {
+		if l.Type == sigType {
+			sigs = append(sigs, l.Signatures...)
+		}
+	}
+	return sigs, nil
+}
+
+// store writes the signature lists of a database, deleting the variable when
+// it holds no signatures.
+func (db secureBootDatabase) store(img *firmware.Image, lists []firmware.SignatureList) {
+	data := firmware.EncodeSignatureLists(lists)
+	if len(data) == 0 {
+		img.Delete(db.Id, db.Guid)
+		return
+	}
+
+	img.Set(firmware.Variable{
+		Name:       db.Id,
+		Guid:       db.Guid,
+		Attributes: firmware.SecureBootAttributes,
+		Data:       data,
+		TimeStamp:  firmware.Timestamp(time.Now()),
+	})
+}
+
+// modify applies fn to the signature lists of a database.
+func (db secureBootDatabase) modify(img *firmware.Image, fn func([]firmware.SignatureList) ([]firmware.SignatureList, error)) error {
+	var lists []firmware.SignatureList
+	if v, ok := img.Get(db.Id, db.Guid); ok {
+		var err error
+		if lists, err = firmware.ParseSignatureLists(v.Data); err != nil {
+			return fmt.Errorf("%s: %w", db.Id, err)
+		}
+	}
+
+	lists, err := fn(lists)
+	if err != nil {
+		return err
+	}
+
+	db.store(img, lists)
+	return nil
+}
+
+// removeSignature drops the index-th signature of the given type.
+func removeSignature(lists []firmware.SignatureList, sigType efiguid.GUID, index int) ([]firmware.SignatureList, bool) {
+	for i := range lists {
+		if lists[i].Type != sigType {
+			continue
+		}
+		if index < len(lists[i].Signatures) {
+			lists[i].Signatures = slices.Delete(lists[i].Signatures, index, index+1)
+			return lists, true
+		}
+		index -= len(lists[i].Signatures)
+	}
+	return lists, false
+}
+
+// defaultKeys returns the default signature lists of a database, read from
+// the EFI signature list named after it in SecureBootDefaultKeys.
+func (r *RedfishServer) defaultKeys(db secureBootDatabase) ([]byte, error) {
+	if r.Config.SecureBootDefaultKeys == "" {
+		return nil, fmt.Errorf("no default keys are configured")
+	}
+
+	b, err := os.ReadFile(filepath.Join(r.Config.SecureBootDefaultKeys, db.Id+".esl"))
+	if err != nil {
+		return nil, fmt.Errorf("reading default %s: %w", db.Id, err)
+	}
+	if _, err := firmware.ParseSignatureLists(b); err != nil {
+		return nil, fmt.Errorf("default %s: %w", db.Id, err)
+	}
+	return b, nil
+}
+
+// resetKeysTypes returns the ResetKeysType values offered for the given
+// databases. Resetting to defaults requires configured default keys.
+func (r *RedfishServer) resetKeysTypes(all bool) []string {
+	var types []string
+	if r.Config.SecureBootDefaultKeys != "" {
+		types = append(types, resetAllKeysToDefault)
+	}
+	types = append(types, deleteAllKeys)
+	if all {
+		types = append(types, deletePK)
+	}
+	return types
+}
+
+// resetKeys resets the given databases to the configured defaults, or
+// deletes them.
+func (r *RedfishServer) resetKeys(img *firmware.Image, dbs []secureBootDatabase, resetType string) error {
+	for _, db := range dbs {
+		switch resetType {
+		case resetAllKeysToDefault:
+			def, err := r.defaultKeys(db)
+			if err != nil {
+				return err
+			}
+			img.Set(firmware.Variable{
+				Name:       db.Id,
+				Guid:       db.Guid,
+				Attributes: firmware.SecureBootAttributes,
+				Data:       def,
+				TimeStamp:  firmware.Timestamp(time.Now()),
+			})
+		case deleteAllKeys:
+			img.Delete(db.Id, db.Guid)
+		case deletePK:
+			if db.Id == "PK" {
+				img.Delete(db.Id, db.Guid)
+			}
+		default:
+			return fmt.Errorf("unsupported ResetKeysType %q", resetType)
+		}
+	}
+	return nil
+}
+
+// secureBootResource is the Redfish SecureBoot resource.
+type secureBootResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Actions               *secureBootActions `json:"Actions,omitempty"`
+	Id                    *string            `json:"Id,omitempty"`
+	Name                  *string            `json:"Name,omitempty"`
+	SecureBootCurrentBoot *string            `json:"SecureBootCurrentBoot,omitempty"`
+	SecureBootDatabases   *IdRef             `json:"SecureBootDatabases,omitempty"`
+	SecureBootEnable      *bool              `json:"SecureBootEnable,omitempty"`
+	SecureBootMode        *string            `json:"SecureBootMode,omitempty"`
+}
+
+type secureBootActions struct {
+	ResetKeys *resetKeysAction `json:"#SecureBoot.ResetKeys,omitempty"`
+}
+
+type secureBootDatabaseActions struct {
+	ResetKeys *resetKeysAction `json:"#SecureBootDatabase.ResetKeys,omitempty"`
+}
+
+type resetKeysAction struct {
+	ResetKeysTypeRedfishAllowableValues *[]string `json:"ResetKeysType@Redfish.AllowableValues,omitempty"`
+	Target                              *string   `json:"target"`
+}
+
+// secureBootDatabaseResource is the Redfish SecureBootDatabase resource.
+type secureBootDatabaseResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Actions      *secureBootDatabaseActions `json:"Actions,omitempty"`
+	Certificates *IdRef                     `json:"Certificates,omitempty"`
+	DatabaseId   *string                    `json:"DatabaseId,omitempty"`
+	Id           *string                    `json:"Id,omitempty"`
+	Name         *string                    `json:"Name,omitempty"`
+	Signatures   *IdRef                     `json:"Signatures,omitempty"`
+}
+
+// signatureResource is the Redfish Signature resource.
+type signatureResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Id                    *string `json:"Id,omitempty"`
+	Name                  *string `json:"Name,omitempty"`
+	SignatureString       *string `json:"SignatureString,omitempty"`
+	SignatureType         *string `json:"SignatureType,omitempty"`
+	SignatureTypeRegistry *string `json:"SignatureTypeRegistry,omitempty"`
+	UefiSignatureOwner    *string `json:"UefiSignatureOwner,omitempty"`
+}
+
+func secureBootPath(systemId string) string {
+	return fmt.Sprintf("/redfish/v1/Systems/%s/SecureBoot", systemId)
+}
+
+func secureBootDatabasePath(systemId, databaseId string) string {
+	return fmt.Sprintf("%s/SecureBootDatabases/%s", secureBootPath(systemId), databaseId)
+}
+
+// secureBootFirmware reads the firmware image of the system of the request,
+// responding with an error if it cannot hold Secure Boot keys.
+func (r *RedfishServer) secureBootFirmware(c *gin.Context) (RedfishSystem, *firmware.Image, bool) {
+	sys, ok := r.lookupSystem(c, c.Param("ComputerSystemId"))
+	if !ok {
+		return sys, nil, false
+	}
+
+	img, err := r.readSystemFirmware(sys)
+	if errors.Is(err, os.ErrNotExist) {
+		resourceNotFound(c, "SecureBoot", c.Param("ComputerSystemId"))
+		return sys, nil, false
+	} else if err != nil {
+		c.JSON(500, redfishError(err))
+		return sys, nil, false
+	}
+
+	if !img.Authenticated() {
+		// Firmware without authenticated variables has no Secure Boot.
+		resourceNotFound(c, "SecureBoot", c.Param("ComputerSystemId"))
+		return sys, nil, false
+	}
+
+	return sys, img, true
+}
+
+// secureBootDatabaseFor resolves the database of the request.
+func secureBootDatabaseFor(c *gin.Context) (secureBootDatabase, bool) {
+	db, ok := findSecureBootDatabase(c.Param("DatabaseId"))
+	if !ok {
+		resourceNotFound(c, "SecureBootDatabase", c.Param("DatabaseId"))
+	}
+	return db, ok
+}
+
+// updateSecureBoot applies fn to the firmware image of a system, records the
+// change and responds with an error if it failed. fn reports entries it does
+// not find with ResourceNotFound.
+func (r *RedfishServer) updateSecureBoot(c *gin.Context, sys RedfishSystem, action, detail string, fn func(*firmware.Image) error) bool {
+	err := r.updateSystemFirmware(sys, fn)
+	user, _ := contextUser(c)
+	r.audit(user, sys, action, detail, err)
+	switch {
+	case isMessage(err, baseRegistry+"ResourceNotFound"):
+		c.JSON(404, redfishError(err))
+		return false
+	case err != nil:
+		c.JSON(500, redfishError(err))
+		return false
+	}
+	return true
+}
+
+// GetSecureBoot reports the Secure Boot state a system boots with, read from
+// its firmware image.
+func (r *RedfishServer) GetSecureBoot(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	_, img, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	enabled := false
+	if v, ok := img.Get("SecureBootEnable", secureBootEnableGuid); ok && len(v.Data) > 0 {
+		enabled = v.Data[0] != 0
+	}
+
+	mode := "SetupMode"
+	if _, ok := img.Get("PK", firmware.GlobalVariableGuid); ok {
+		mode = "UserMode"
+	}
+
+	current := "Disabled"
+	if enabled && mode == "UserMode" {
+		current = "Enabled"
+	}
+
+	c.JSON(200, &secureBootResource{
+		OdataId:               ptr(secureBootPath(systemId)),
+		OdataType:             ptr("#SecureBoot.v1_1_0.SecureBoot"),
+		Id:                    ptr("SecureBoot"),
+		Name:                  ptr("UEFI Secure Boot"),
+		SecureBootEnable:      ptr(enabled),
+		SecureBootMode:        ptr(mode),
+		SecureBootCurrentBoot: ptr(current),
+		SecureBootDatabases:   &IdRef{OdataId: ptr(secureBootPath(systemId) + "/SecureBootDatabases")},
+		Actions: &secureBootActions{
+			ResetKeys: &resetKeysAction{
+				ResetKeysTypeRedfishAllowableValues: ptr(r.resetKeysTypes(true)),
+				Target:                              ptr(secureBootPath(systemId) + "/Actions/SecureBoot.ResetKeys"),
+			},
+		},
+	})
+}
+
+// SetSecureBoot turns Secure Boot on or off in the firmware image of a
+// system.
+func (r *RedfishServer) SetSecureBoot(c *gin.Context) {
+	req := secureBootResource{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sys, _, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	if req.SecureBootEnable != nil {
+		value := byte(0)
+		if *req.SecureBootEnable {
+			value = 1
+		}
+
+		ok := r.updateSecureBoot(c, sys, "SetSecureBootEnable", strconv.FormatBool(*req.SecureBootEnable), func(img *firmware.Image) error {
+			img.Set(firmware.Variable{
+				Name:       "SecureBootEnable",
+				Guid:       secureBootEnableGuid,
+				Attributes: efivario.NonVolatile | efivario.BootServiceAccess,
+				Data:       []byte{value},
+			})
+			return nil
+		})
+		if !ok {
+			return
+		}
+	}
+
+	r.GetSecureBoot(c)
+}
+
+// ResetSecureBootKeys resets or deletes every Secure Boot key database of a
+// system.
+func (r *RedfishServer) ResetSecureBootKeys(c *gin.Context) {
+	req := struct {
+		ResetKeysType string `json:"ResetKeysType"`
+	}{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !slices.Contains(r.resetKeysTypes(true), req.ResetKeysType) {
+		invalidProperty(c, "ResetKeysType", "ActionParameterValueNotInList", req.ResetKeysType, "ResetKeysType", "SecureBoot.ResetKeys")
+		return
+	}
+
+	sys, _, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	if !r.updateSecureBoot(c, sys, "ResetSecureBootKeys", req.ResetKeysType, func(img *firmware.Image) error {
+		return r.resetKeys(img, secureBootDatabases, req.ResetKeysType)
+	}) {
+		return
+	}
+
+	c.Status(204)
+}
+
+// ListSecureBootDatabases lists the Secure Boot key databases.
+func (r *RedfishServer) ListSecureBootDatabases(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	if _, ok := r.lookupSystem(c, systemId); !ok {
+		return
+	}
+
+	ids := []IdRef{}
+	for _, db := range secureBootDatabases {
+		ids = append(ids, IdRef{OdataId: ptr(secureBootDatabasePath(systemId, db.Id))})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#SecureBootDatabaseCollection.SecureBootDatabaseCollection"),
+		OdataType:         "#SecureBootDatabaseCollection.SecureBootDatabaseCollection",
+		Name:              ptr("UEFI Secure Boot Database Collection"),
+		OdataId:           secureBootPath(systemId) + "/SecureBootDatabases",
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// GetSecureBootDatabase links the certificates and signatures of a database.
+func (r *RedfishServer) GetSecureBootDatabase(c *gin.Context) {
+	systemId := c.Param("ComputerSystemId")
+
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := r.lookupSystem(c, systemId); !ok {
+		return
+	}
+
+	path := secureBootDatabasePath(systemId, db.Id)
+
+	c.JSON(200, &secureBootDatabaseResource{
+		OdataId:      ptr(path),
+		OdataType:    ptr("#SecureBootDatabase.v1_0_1.SecureBootDatabase"),
+		Id:           ptr(db.Id),
+		DatabaseId:   ptr(db.Id),
+		Name:         ptr(db.Id + " Database"),
+		Certificates: &IdRef{OdataId: ptr(path + "/Certificates")},
+		Signatures:   &IdRef{OdataId: ptr(path + "/Signatures")},
+		Actions: &secureBootDatabaseActions{
+			ResetKeys: &resetKeysAction{
+				ResetKeysTypeRedfishAllowableValues: ptr(r.resetKeysTypes(false)),
+				Target:                              ptr(path + "/Actions/SecureBootDatabase.ResetKeys"),
+			},
+		},
+	})
+}
+
+// ResetSecureBootDatabaseKeys resets or deletes a single key database.
+func (r *RedfishServer) ResetSecureBootDatabaseKeys(c *gin.Context) {
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return
+	}
+
+	req := struct {
+		ResetKeysType string `json:"ResetKeysType"`
+	}{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !slices.Contains(r.resetKeysTypes(false), req.ResetKeysType) {
+		invalidProperty(c, "ResetKeysType", "ActionParameterValueNotInList", req.ResetKeysType, "ResetKeysType", "SecureBootDatabase.ResetKeys")
+		return
+	}
+
+	sys, _, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	if !r.updateSecureBoot(c, sys, "ResetSecureBootKeys", db.Id+" "+req.ResetKeysType, func(img *firmware.Image) error {
+		return r.resetKeys(img, []secureBootDatabase{db}, req.ResetKeysType)
+	}) {
+		return
+	}
+
+	c.Status(204)
+}
+
+// ListSecureBootCertificates lists the X.509 certificates of a database.
+func (r *RedfishServer) ListSecureBootCertificates(c *gin.Context) {
+	r.listSecureBootEntries(c, firmware.CertX509Guid, "Certificates", "#CertificateCollection.CertificateCollection", "Certificate Collection")
+}
+
+// ListSecureBootSignatures lists the SHA-256 hashes of a database.
+func (r *RedfishServer) ListSecureBootSignatures(c *gin.Context) {
+	r.listSecureBootEntries(c, firmware.CertSha256Guid, "Signatures", "#SignatureCollection.SignatureCollection", "Signature Collection")
+}
+
+func (r *RedfishServer) listSecureBootEntries(c *gin.Context, sigType efiguid.GUID, collection, odataType, name string) {
+	systemId := c.Param("ComputerSystemId")
+
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return
+	}
+
+	_, img, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	sigs, err := db.signatures(img, sigType)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	path := secureBootDatabasePath(systemId, db.Id) + "/" + collection
+
+	ids := []IdRef{}
+	for i := range sigs {
+		ids = append(ids, IdRef{OdataId: ptr(fmt.Sprintf("%s/%d", path, i+1))})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataType:         odataType,
+		Name:              ptr(name),
+		OdataId:           path,
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// secureBootEntry returns the signature of the given type addressed by the
+// request, numbered from 1.
+func (r *RedfishServer) secureBootEntry(c *gin.Context, sigType efiguid.GUID, param string) (firmware.Signature, bool) {
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return firmware.Signature{}, false
+	}
+
+	_, img, ok := r.secureBootFirmware(c)
+	if !ok {
+		return firmware.Signature{}, false
+	}
+
+	sigs, err := db.signatures(img, sigType)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return firmware.Signature{}, false
+	}
+
+	i, err := strconv.Atoi(c.Param(param))
+	if err != nil || i < 1 || i > len(sigs) {
+		resourceNotFound(c, strings.TrimSuffix(param, "Id"), c.Param(param))
+		return firmware.Signature{}, false
+	}
+
+	return sigs[i-1], true
+}
+
+// GetSecureBootCertificate reports an X.509 certificate of a database.
+func (r *RedfishServer) GetSecureBootCertificate(c *gin.Context) {
+	sig, ok := r.secureBootEntry(c, firmware.CertX509Guid, "CertificateId")
+	if !ok {
+		return
+	}
+
+	cert, err := x509.ParseCertificate(sig.Data)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	resp := certificateResourceFor(cert)
+	resp.OdataId = ptr(fmt.Sprintf("%s/Certificates/%s", secureBootDatabasePath(c.Param("ComputerSystemId"), c.Param("DatabaseId")), c.Param("CertificateId")))
+	resp.Id = ptr(c.Param("CertificateId"))
+	resp.Name = ptr(cert.Subject.CommonName)
+
+	c.JSON(200, &resp)
+}
+
+// GetSecureBootSignature reports a SHA-256 hash of a database.
+func (r *RedfishServer) GetSecureBootSignature(c *gin.Context) {
+	sig, ok := r.secureBootEntry(c, firmware.CertSha256Guid, "SignatureId")
+	if !ok {
+		return
+	}
+
+	c.JSON(200, &signatureResource{
+		OdataId:               ptr(fmt.Sprintf("%s/Signatures/%s", secureBootDatabasePath(c.Param("ComputerSystemId"), c.Param("DatabaseId")), c.Param("SignatureId"))),
+		OdataType:             ptr("#Signature.v1_0_2.Signature"),
+		Id:                    ptr(c.Param("SignatureId")),
+		Name:                  ptr("SHA-256 Signature"),
+		SignatureString:       ptr(hex.EncodeToString(sig.Data)),
+		SignatureType:         ptr("EFI_CERT_SHA256_GUID"),
+		SignatureTypeRegistry: ptr("UEFI"),
+		UefiSignatureOwner:    ptr(strings.ToLower(sig.Owner.String())),
+	})
+}
+
+// EnrollSecureBootCertificate adds a PEM encoded X.509 certificate to a
+// database. PK holds a single certificate, which is replaced.
+func (r *RedfishServer) EnrollSecureBootCertificate(c *gin.Context) {
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return
+	}
+
+	req := struct {
+		CertificateString string `json:"CertificateString"`
+		CertificateType   string `json:"CertificateType"`
+	}{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.CertificateType != "" && req.CertificateType != "PEM" {
+		invalidProperty(c, "CertificateType", "PropertyValueNotInList", req.CertificateType, "CertificateType")
+		return
+	}
+
+	block, _ := pem.Decode([]byte(req.CertificateString))
+	if block == nil || block.Type != "CERTIFICATE" {
+		invalidProperty(c, "CertificateString", "PropertyValueError", "CertificateString")
+		return
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		invalidProperty(c, "CertificateString", "PropertyValueError", "CertificateString")
+		return
+	}
+
+	sys, _, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	if !r.updateSecureBoot(c, sys, "EnrollSecureBootCertificate", db.Id+" "+cert.Subject.String(), func(img *firmware.Image) error {
+		return db.modify(img, func(lists []firmware.SignatureList) ([]firmware.SignatureList, error) {
+			if db.Id == "PK" {
+				lists = nil
+			}
+			return append(lists, firmware.SignatureList{
+				Type:       firmware.CertX509Guid,
+				Signatures: []firmware.Signature{{Owner: signatureOwner, Data: cert.Raw}},
+			}), nil
+		})
+	}) {
+		return
+	}
+
+	_, img, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	sigs, _ := db.signatures(img, firmware.CertX509Guid)
+	location := fmt.Sprintf("%s/Certificates/%d", secureBootDatabasePath(c.Param("ComputerSystemId"), db.Id), len(sigs))
+
+	resp := certificateResourceFor(cert)
+	resp.OdataId = ptr(location)
+	resp.Id = ptr(strconv.Itoa(len(sigs)))
+	resp.Name = ptr(cert.Subject.CommonName)
+
+	c.Header("Location", location)
+	c.JSON(201, &resp)
+}
+
+// EnrollSecureBootSignature adds a SHA-256 hash to a database, typically to
+// revoke an image through dbx.
+func (r *RedfishServer) EnrollSecureBootSignature(c *gin.Context) {
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return
+	}
+
+	req := signatureResource{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.SignatureType != nil && *req.SignatureType != "EFI_CERT_SHA256_GUID" {
+		invalidProperty(c, "SignatureType", "PropertyValueNotInList", *req.SignatureType, "SignatureType")
+		return
+	}
+
+	if req.SignatureString == nil {
+		propertyMissing(c, "SignatureString")
+		return
+	}
+
+	hash, err := hex.DecodeString(*req.SignatureString)
+	if err != nil || len(hash) != 32 {
+		invalidProperty(c, "SignatureString", "PropertyValueFormatError", *req.SignatureString, "SignatureString")
+		return
+	}
+
+	owner := signatureOwner
+	if req.UefiSignatureOwner != nil {
+		if owner, err = efiguid.FromString(*req.UefiSignatureOwner); err != nil {
+			invalidProperty(c, "UefiSignatureOwner", "PropertyValueFormatError", *req.UefiSignatureOwner, "UefiSignatureOwner")
+			return
+		}
+	}
+
+	sys, _, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	if !r.updateSecureBoot(c, sys, "EnrollSecureBootSignature", db.Id+" "+*req.SignatureString, func(img *firmware.Image) error {
+		return db.modify(img, func(lists []firmware.SignatureList) ([]firmware.SignatureList, error) {
+			for i := range lists {
+				if lists[i].Type == firmware.CertSha256Guid {
+					lists[i].Signatures = append(lists[i].Signatures, firmware.Signature{Owner: owner, Data: hash})
+					return lists, nil
+				}
+			}
+			return append(lists, firmware.SignatureList{
+				Type:       firmware.CertSha256Guid,
+				Signatures: []firmware.Signature{{Owner: owner, Data: hash}},
+			}), nil
+		})
+	}) {
+		return
+	}
+
+	c.Status(204)
+}
+
+// DeleteSecureBootCertificate removes an X.509 certificate from a database.
+func (r *RedfishServer) DeleteSecureBootCertificate(c *gin.Context) {
+	r.deleteSecureBootEntry(c, firmware.CertX509Guid, "CertificateId")
+}
+
+// DeleteSecureBootSignature removes a SHA-256 hash from a database.
+func (r *RedfishServer) DeleteSecureBootSignature(c *gin.Context) {
+	r.deleteSecureBootEntry(c, firmware.CertSha256Guid, "SignatureId")
+}
+
+func (r *RedfishServer) deleteSecureBootEntry(c *gin.Context, sigType efiguid.GUID, param string) {
+	db, ok := secureBootDatabaseFor(c)
+	if !ok {
+		return
+	}
+
+	sys, _, ok := r.secureBootFirmware(c)
+	if !ok {
+		return
+	}
+
+	// The entry is looked up while the image is locked, so a concurrent
+	// change cannot shift the entry the index refers to.
+	kind, id := strings.TrimSuffix(param, "Id"), c.Param(param)
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 1 {
+		resourceNotFound(c, kind, id)
+		return
+	}
+
+	if !r.updateSecureBoot(c, sys, "DeleteSecureBootKey", fmt.Sprintf("%s %s %d", db.Id, kind, i), func(img *firmware.Image) error {
+		return db.modify(img, func(lists []firmware.SignatureList) ([]firmware.SignatureList, error) {
+			lists, found := removeSignature(lists, sigType, i-1)
+			if !found {
+				return nil, newMessageError(baseRegistry+"ResourceNotFound", kind, id)
+			}
+			return lists, nil
+		})
+	}) {
+		return
+	}
+
+	c.Status(204)
+}
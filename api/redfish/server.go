@@ -3,14 +3,20 @@ package redfish
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/appkins-org/go-redfish-uefi/pkg/certs"
 	"github.com/gin-gonic/gin"
 	"github.com/ubiquiti-community/go-unifi/unifi"
 )
@@ -44,15 +50,139 @@ type RedfishServerConfig struct {
 	UnifiEndpoint string
 	UnifiSite     string
 	UnifiDevice   string
+
+	// PoeBudget overrides the switch reported total_max_power in watts.
+	PoeBudget float64
+	// PoeExpectedDraw is the draw in watts assumed for systems that do not
+	// configure their own expected_draw.
+	PoeExpectedDraw float64
+
+	// NetworkModes maps network mode names to UniFi port profile names or IDs
+	// for every system.
+	NetworkModes map[string]string
+
+	// QuarantineNetworkMode is the network mode quarantined systems are moved
+	// to. Quarantine only blocks the client when empty.
+	QuarantineNetworkMode string
+
+	// AuditLog is the path operator actions are appended to.
+	AuditLog string
+
+	// TaskRetention is how long finished tasks are kept.
+	TaskRetention time.Duration
+
+	// FirmwareRepository is the directory firmware uploads are staged in.
+	// Uploads are disabled when empty.
+	FirmwareRepository string
+	// FirmwareUploadMaxSize is the size in bytes uploads are limited to.
+	FirmwareUploadMaxSize int64
+
+	// EventLogDirectory is where the event log of every system is kept. The
+	// event logs are disabled when empty.
+	EventLogDirectory string
+	// EventLogMaxSize is the size in bytes an event log is rotated at, and
+	// EventLogMaxFiles how many rotated files are kept.
+	EventLogMaxSize  int64
+	EventLogMaxFiles int
+
+	// EventSubscriptionsFile is where event subscriptions are stored.
+	EventSubscriptionsFile string
+	// EventRetryAttempts and EventRetryInterval are the initial delivery
+	// retry policy of the EventService. The interval doubles with every
+	// retry.
+	EventRetryAttempts int
+	EventRetryInterval time.Duration
+	// ControllerPollInterval is how often the controller is polled for
+	// power transitions and outages.
+	ControllerPollInterval time.Duration
+
+	// DhcpNetwork is the name or ID of the network whose DHCP boot options
+	// point at this daemon.
+	DhcpNetwork      string
+	DhcpBootServer   string
+	DhcpBootFilename string
+	// DhcpReconcile corrects DHCP boot option drift on startup.
+	DhcpReconcile bool
+
+	// Models overrides the built-in hardware description of model families.
+	Models map[string]SystemModel
+	// FingerprintModels maps controller fingerprint device IDs to the model
+	// family they identify.
+	FingerprintModels map[string]string
+	// ExcludedDeviceCategories lists fingerprint device categories of clients
+	// that are not hosts, such as cameras.
+	ExcludedDeviceCategories []int
+	// ExcludedOUIs lists OUIs of clients that are not hosts, in addition to
+	// the built-in access point and camera vendors.
+	ExcludedOUIs []string
+
+	// AuthUsername and AuthPassword are the credentials accepted by HTTP
+	// Basic authentication and session login. Authentication is disabled
+	// when AuthUsername is empty.
+	AuthUsername string
+	AuthPassword string
+	// AccountsFile is the path local accounts are persisted to. They are kept
+	// in memory only when empty.
+	AccountsFile string
+	// SessionTimeout is the idle timeout of sessions. Defaults to 30 minutes.
+	SessionTimeout time.Duration
+
+	// TftpRootDirectory is the root of the TFTP server holding the firmware
+	// images, shared and per MAC address.
+	TftpRootDirectory string
+
+	// Port is the port the API is served on.
+	Port int
+	// Certificates serves the HTTPS certificate. The API is served over plain
+	// HTTP and the CertificateService cannot replace it when nil.
+	Certificates *certs.Manager
+
+	// VirtualMediaBaseURL is the URL systems fetch virtual media from,
+	// ending before mediaPrefix.
+	VirtualMediaBaseURL string
+	// VirtualMediaCacheDirectory is where media inserted with the Upload
+	// transfer method is downloaded to. Only Stream is supported when empty.
+	VirtualMediaCacheDirectory string
+	// VirtualMediaMaxSize is the size in bytes downloaded media is limited
+	// to. Defaults to 8 GiB.
+	VirtualMediaMaxSize int64
+
+	// SecureBootDefaultKeys is the directory holding the EFI signature lists
+	// Secure Boot key databases are reset to, named after the database with
+	// an .esl extension. ResetAllKeysToDefault is not offered when empty.
+	SecureBootDefaultKeys string
+
+	// FirmwareVersion is the version of this daemon reported by its managers.
+	FirmwareVersion string
+
+	// StateFile is the path the properties set through the API are persisted
+	// to. They are kept in memory only when empty.
+	StateFile string
+
+	// Systems holds per-system configuration keyed by switch port index.
+	Systems map[string]RedfishSystem
 }
 
 type RedfishSystem struct {
-	MacAddress string `yaml:"mac"`
-	IpAddress  string `yaml:"ip"`
-	UnifiPort  int    `yaml:"port"`
-	SiteID     string `yaml:"site"`
-	DeviceMac  string `yaml:"device_mac"`
-	PoeMode    string `yaml:"poe_mode"`
+	MacAddress   string  `yaml:"mac" mapstructure:"mac"`
+	IpAddress    string  `yaml:"ip" mapstructure:"ip"`
+	UnifiPort    int     `yaml:"port" mapstructure:"port"`
+	SiteID       string  `yaml:"site" mapstructure:"site"`
+	DeviceMac    string  `yaml:"device_mac" mapstructure:"device_mac"`
+	PoeMode      string  `yaml:"poe_mode" mapstructure:"poe_mode"`
+	ExpectedDraw float64 `yaml:"expected_draw" mapstructure:"expected_draw"`
+
+	PortProfileID string            `yaml:"port_profile" mapstructure:"port_profile"`
+	NetworkModes  map[string]string `yaml:"network_modes" mapstructure:"network_modes"`
+
+	ModelFamily string      `yaml:"model_family" mapstructure:"model_family"`
+	Model       SystemModel `yaml:"model" mapstructure:"model"`
+
+	Quarantined bool `yaml:"-" mapstructure:"-"`
+
+	LinkUp    bool   `yaml:"-" mapstructure:"-"`
+	FixedIP   string `yaml:"-" mapstructure:"-"`
+	NetworkID string `yaml:"-" mapstructure:"-"`
 }
 
 func (r *RedfishSystem) GetPowerState() *PowerState {
@@ -68,24 +198,41 @@ func (r *RedfishSystem) GetPowerState() *PowerState {
 	return &state
 }
 
-func redfishError(err error) *RedfishError {
-	return &RedfishError{
-		Error: RedfishErrorError{
-			Message: ptr(err.Error()),
-			Code:    ptr("Base.1.0.GeneralError"),
-		},
-	}
-}
-
 type RedfishServer struct {
-	Systems map[int]RedfishSystem
+	// Systems are the systems keyed by switch port. systemsMu guards them,
+	// as tasks read them while handlers refresh them.
+	Systems   map[int]RedfishSystem
+	systemsMu sync.RWMutex
 
 	Config *RedfishServerConfig
 
-	client *unifi.Client
+	client     *unifi.Client
+	httpClient *http.Client
+
+	state      *stateStore
+	sessions   *sessionStore
+	accounts   *accountStore
+	tasks      *taskStore
+	logs       *eventLogStore
+	repository *firmwareRepository
+	events     *eventService
+	index      systemIndex
+	monitor    controllerMonitor
+
+	// deviceMu serializes updates of the switch, which replace its whole
+	// port override table, so concurrent tasks do not undo each other.
+	deviceMu sync.Mutex
+	// firmwareMu serializes the read-modify-write cycles of firmware images,
+	// so a firmware update and a BIOS or Secure Boot change of the same
+	// image do not drop each other's variables.
+	firmwareMu sync.Mutex
+
+	downloads     mediaDownloads
+	dhcpHealth    dhcpHealth
+	softwareItems softwareItemCache
 }
 
-func NewRedfishServer(cfg RedfishServerConfig) ServerInterface {
+func NewRedfishServer(cfg RedfishServerConfig) *RedfishServer {
 	client := unifi.Client{}
 
 	if err := client.SetBaseURL(cfg.UnifiEndpoint); err != nil {
@@ -120,29 +267,68 @@ func NewRedfishServer(cfg RedfishServerConfig) ServerInterface {
 		panic(fmt.Sprintf("failed to login: %s", err))
 	}
 
+	state, err := loadState(cfg.StateFile)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load state: %s", err))
+	}
+
+	accounts, err := loadAccounts(cfg.AccountsFile)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load accounts: %s", err))
+	}
+
+	events, err := loadEventService(cfg.EventSubscriptionsFile, cfg.EventRetryAttempts, cfg.EventRetryInterval)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load event subscriptions: %s", err))
+	}
+
+	repository, err := loadFirmwareRepository(cfg.FirmwareRepository, cfg.FirmwareUploadMaxSize)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load firmware repository: %s", err))
+	}
+
 	rfSystems := make(map[int]RedfishSystem)
 
 	server := &RedfishServer{
-		Systems: rfSystems,
-		client:  &client,
-		Config:  &cfg,
+		Systems:    rfSystems,
+		client:     &client,
+		httpClient: httpClient,
+		Config:     &cfg,
+		state:      state,
+		sessions:   newSessionStore(cfg.SessionTimeout),
+		accounts:   accounts,
+		tasks:      newTaskStore(cfg.TaskRetention),
+		logs:       newEventLogStore(cfg.EventLogDirectory, cfg.EventLogMaxSize, cfg.EventLogMaxFiles),
+		events:     events,
+		repository: repository,
+	}
+
+	if !server.authEnabled() {
+		log.Printf("auth: no credentials configured, the API is unauthenticated")
 	}
 
 	server.refreshSystems(context.Background())
 
+	if cfg.DhcpReconcile {
+		if _, drift, err := server.reconcileDhcpBoot(context.Background(), true); err != nil {
+			log.Printf("dhcp: reconciling boot options: %v", err)
+		} else if len(drift) > 0 {
+			log.Printf("dhcp: boot options still drifted: %v", drift)
+		}
+	}
+
 	return server
 }
 
 func (r *RedfishServer) refreshSystems(ctx context.Context) (err error) {
 	device, err := r.client.GetDeviceByMAC(ctx, r.Config.UnifiSite, r.Config.UnifiDevice)
+	r.noteController(err)
 	if err != nil {
-		panic(err)
-	}
-
-	if device.PortOverrides == nil {
-		panic("no port overrides found")
+		return controllerUnreachable(r.Config.UnifiEndpoint, err)
 	}
 
+	// A switch without port overrides simply has no systems.
+	r.systemsMu.Lock()
 	for _, port := range device.PortOverrides {
 
 		sys, ok := r.Systems[port.PortIDX]
@@ -154,30 +340,107 @@ func (r *RedfishServer) refreshSystems(ctx context.Context) (err error) {
 			}
 		}
 		sys.PoeMode = port.PoeMode
+		sys.PortProfileID = port.PortProfileID
+		sys.LinkUp = false
 
 		r.Systems[port.PortIDX] = sys
 	}
+	r.systemsMu.Unlock()
 
-	if clients, err := r.client.ListActiveClients(ctx, r.Config.UnifiSite); err != nil {
-		panic(err)
-	} else {
-		for _, c := range clients {
+	for _, port := range device.PortOverrides {
+		r.notePoeMode(port.PortIDX, port.PoeMode)
+	}
 
-			if c.UplinkMac == r.Config.UnifiDevice {
+	clients, err := r.client.ListActiveClients(ctx, r.Config.UnifiSite)
+	if err != nil {
+		r.noteController(err)
+		return err
+	}
 
-				sys, ok := r.Systems[c.SwPort]
-				if !ok {
-					sys = RedfishSystem{
-						UnifiPort: c.SwPort,
-						DeviceMac: c.UplinkMac,
-						SiteID:    c.SiteID,
-					}
+	r.systemsMu.Lock()
+
+	hosts := make(map[int]bool)
+	nonHosts := make(map[int]bool)
+
+	for _, c := range clients {
+
+		if c.UplinkMac == r.Config.UnifiDevice {
+
+			family, host := r.classifyClient(c)
+			if !host {
+				nonHosts[c.SwPort] = true
+				continue
+			}
+			hosts[c.SwPort] = true
+
+			sys, ok := r.Systems[c.SwPort]
+			if !ok {
+				sys = RedfishSystem{
+					UnifiPort: c.SwPort,
+					DeviceMac: c.UplinkMac,
+					SiteID:    c.SiteID,
 				}
+			}
+
+			sys.MacAddress = c.Mac
+			sys.IpAddress = c.IP
+			sys.NetworkID = c.NetworkID
+			sys.LinkUp = true
+			sys.ModelFamily = family
+
+			r.Systems[c.SwPort] = sys
+		}
+	}
+
+	// Ports serving only access points or cameras are not systems, unless
+	// configured as one.
+	for port := range nonHosts {
+		if conf, ok := r.Config.Systems[strconv.Itoa(port)]; hosts[port] || ok && conf.MacAddress != "" {
+			continue
+		}
+		delete(r.Systems, port)
+	}
+
+	for i, sys := range r.Systems {
+		if sys.MacAddress != "" {
+			continue
+		}
+		if conf, ok := r.Config.Systems[strconv.Itoa(i)]; ok && conf.MacAddress != "" {
+			sys.MacAddress = conf.MacAddress
+			r.Systems[i] = sys
+		}
+	}
+
+	r.index.update(r.Systems)
+	r.systemsMu.Unlock()
+
+	users, err := r.client.ListUser(ctx, r.Config.UnifiSite)
+	if err != nil {
+		return
+	}
+
+	r.systemsMu.Lock()
+	defer r.systemsMu.Unlock()
 
-				sys.MacAddress = c.Mac
-				sys.IpAddress = c.IP
+	for _, u := range users {
+		for i, sys := range r.Systems {
+			if sys.MacAddress != "" && strings.EqualFold(sys.MacAddress, u.MAC) {
+				sys.Quarantined = u.Blocked
+				sys.FixedIP = ""
+				if u.UseFixedIP {
+					sys.FixedIP = u.FixedIP
+				}
+				if sys.NetworkID == "" {
+					sys.NetworkID = u.NetworkID
+				}
+				r.Systems[i] = sys
 
-				r.Systems[c.SwPort] = sys
+				// Aliases set in the controller UI become the system name.
+				if u.Name != "" && u.Name != r.state.system(i).Name {
+					if err := r.state.updateSystem(i, func(st *systemState) { st.Name = u.Name }); err != nil {
+						log.Printf("state: importing name of system %d: %v", i, err)
+					}
+				}
 			}
 		}
 	}
@@ -185,18 +448,53 @@ func (r *RedfishServer) refreshSystems(ctx context.Context) (err error) {
 	return
 }
 
+// system returns the system on a switch port.
+func (r *RedfishServer) system(port int) (RedfishSystem, bool) {
+	r.systemsMu.RLock()
+	defer r.systemsMu.RUnlock()
+
+	sys, ok := r.Systems[port]
+	return sys, ok
+}
+
+// putSystem stores a system under its switch port.
+func (r *RedfishServer) putSystem(sys RedfishSystem) {
+	r.systemsMu.Lock()
+	defer r.systemsMu.Unlock()
+
+	r.Systems[sys.UnifiPort] = sys
+}
+
+// systems returns a copy of the systems keyed by switch port, which tasks
+// may iterate while handlers refresh them.
+func (r *RedfishServer) systems() map[int]RedfishSystem {
+	r.systemsMu.RLock()
+	defer r.systemsMu.RUnlock()
+
+	return maps.Clone(r.Systems)
+}
+
 func (r *RedfishServer) updateDevicePort(ctx context.Context, portIdx int, poeMode string) (device *unifi.Device, err error) {
+	r.deviceMu.Lock()
+	defer r.deviceMu.Unlock()
+
 	device, err = r.client.GetDeviceByMAC(ctx, r.Config.UnifiSite, r.Config.UnifiDevice)
 	if err != nil {
 		return
 	}
-	for i, p := range device.PortOverrides {
-		if p.PortIDX == portIdx {
-			device.PortOverrides[i].PoeMode = poeMode
-			device.PortOverrides[i].StpPortMode = false
-		}
+	i := slices.IndexFunc(device.PortOverrides, func(p unifi.DevicePortOverrides) bool {
+		return p.PortIDX == portIdx
+	})
+	if i < 0 {
+		return nil, newMessageError(unifiRegistry+"PortNotFound", strconv.Itoa(portIdx), device.MAC)
 	}
+	device.PortOverrides[i].PoeMode = poeMode
+	device.PortOverrides[i].StpPortMode = false
+
 	device, err = r.client.UpdateDevice(ctx, r.Config.UnifiSite, device)
+	if err == nil {
+		r.notePoeMode(portIdx, poeMode)
+	}
 	return
 }
 
@@ -214,7 +512,7 @@ func (r *RedfishServer) getPortState(ctx context.Context, macAddress string, p i
 	})
 
 	if iPort == -1 {
-		err = fmt.Errorf("port %d not found on device %s", p, deviceId)
+		err = newMessageError(unifiRegistry+"PortNotFound", strconv.Itoa(p), macAddress)
 		return
 	}
 
@@ -223,155 +521,301 @@ func (r *RedfishServer) getPortState(ctx context.Context, macAddress string, p i
 	return
 }
 
-// CreateVirtualDisk implements ServerInterface.
+// CreateVirtualDisk implements ServerInterface. Systems have no storage
+// controllers.
 func (r *RedfishServer) CreateVirtualDisk(c *gin.Context, systemId string, storageControllerId string) {
-
-	req := CreateVirtualDiskRequestBody{}
-
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	panic("unimplemented")
+	notImplemented(c)
 }
 
-// DeleteVirtualdisk implements ServerInterface.
+// DeleteVirtualdisk implements ServerInterface. Systems have no storage
+// controllers.
 func (r *RedfishServer) DeleteVirtualdisk(c *gin.Context, systemId string, storageId string) {
-	panic("unimplemented")
+	notImplemented(c)
 }
 
-// EjectVirtualMedia implements ServerInterface.
-func (r *RedfishServer) EjectVirtualMedia(c *gin.Context, managerId string, virtualMediaId string) {
-	panic("unimplemented")
-}
+// GetManager implements ServerInterface.
+func (r *RedfishServer) GetManager(c *gin.Context, managerId string) {
 
-// FirmwareInventory implements ServerInterface.
-func (r *RedfishServer) FirmwareInventory(c *gin.Context) {
+	err := r.refreshSystems(c.Request.Context())
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
 
-	panic("unimplemented")
-}
+	if managerId == serviceManagerId {
+		resp := r.serviceManager()
+		c.JSON(200, &resp)
+		return
+	}
 
-// FirmwareInventoryDownloadImage implements ServerInterface.
-func (r *RedfishServer) FirmwareInventoryDownloadImage(c *gin.Context) {
-	panic("unimplemented")
-}
+	systemIdInt, ok := parseSystemManagerId(managerId)
+	if !ok {
+		resourceNotFound(c, "Manager", managerId)
+		return
+	}
 
-// GetManager implements ServerInterface.
-func (r *RedfishServer) GetManager(c *gin.Context, managerId string) {
-	panic("unimplemented")
-}
+	sys, ok := r.system(systemIdInt)
+	if !ok {
+		resourceNotFound(c, "Manager", managerId)
+		return
+	}
+
+	resp := r.systemManager(strconv.Itoa(systemIdInt), sys)
 
-// GetManagerVirtualMedia implements ServerInterface.
-func (r *RedfishServer) GetManagerVirtualMedia(c *gin.Context, managerId string, virtualMediaId string) {
-	panic("unimplemented")
+	c.JSON(200, &resp)
 }
 
 // GetRoot implements ServerInterface.
 func (r *RedfishServer) GetRoot(c *gin.Context) {
 
-	root := Root{
-		OdataId:        ptr("/redfish/v1"),
-		OdataType:      ptr("#ServiceRoot.v1_11_0.ServiceRoot"),
-		Id:             ptr("RootService"),
-		Name:           ptr("Root Service"),
-		RedfishVersion: ptr("1.11.0"),
-		Systems: &IdRef{
-			OdataId: ptr("/redfish/v1/Systems"),
+	root := serviceRoot{
+		Root: Root{
+			OdataId:        ptr("/redfish/v1"),
+			OdataType:      ptr("#ServiceRoot.v1_11_0.ServiceRoot"),
+			Id:             ptr("RootService"),
+			Name:           ptr("Root Service"),
+			RedfishVersion: ptr("1.11.0"),
+			Managers: &IdRef{
+				OdataId: ptr("/redfish/v1/Managers"),
+			},
+			Systems: &IdRef{
+				OdataId: ptr("/redfish/v1/Systems"),
+			},
+		},
+		Links: &serviceRootLinks{
+			Sessions: &IdRef{
+				OdataId: ptr("/redfish/v1/SessionService/Sessions"),
+			},
+		},
+		AccountService: &IdRef{
+			OdataId: ptr("/redfish/v1/AccountService"),
+		},
+		CertificateService: &IdRef{
+			OdataId: ptr("/redfish/v1/CertificateService"),
+		},
+		SessionService: &IdRef{
+			OdataId: ptr("/redfish/v1/SessionService"),
+		},
+		EventService: &IdRef{
+			OdataId: ptr("/redfish/v1/EventService"),
+		},
+		UpdateService: &IdRef{
+			OdataId: ptr("/redfish/v1/UpdateService"),
+		},
+		Tasks: &IdRef{
+			OdataId: ptr("/redfish/v1/TaskService"),
+		},
+		Registries: &IdRef{
+			OdataId: ptr("/redfish/v1/Registries"),
 		},
 	}
 
 	c.JSON(200, &root)
 }
 
-// GetSoftwareInventory implements ServerInterface.
-func (r *RedfishServer) GetSoftwareInventory(c *gin.Context, softwareId string) {
-	panic("unimplemented")
-}
-
 // GetSystem implements ServerInterface.
 func (r *RedfishServer) GetSystem(c *gin.Context, systemId string) {
-
-	err := r.refreshSystems(c.Request.Context())
-	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	s, ok := r.lookupSystem(c, systemId)
+	if !ok {
 		return
 	}
 
-	systemIdInt, err := strconv.ParseInt(systemId, 10, 64)
-	if err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
+	state := r.state.system(s.UnifiPort)
+
+	name := state.Name
+	if name == "" {
+		name = fmt.Sprintf("System %s", systemId)
 	}
 
-	s := r.Systems[int(systemIdInt)]
+	// The network mode is omitted rather than failing the whole resource
+	// when the port profiles cannot be listed.
+	var networkMode *string
+	if mode, err := r.networkMode(c.Request.Context(), s); err != nil {
+		log.Printf("network mode of system %s: %v", systemId, err)
+	} else {
+		networkMode = &mode
+	}
 
 	resp := ComputerSystem{
 		Id:         &systemId,
 		PowerState: s.GetPowerState(),
 		Links: &SystemLinks{
-			Chassis:   &[]IdRef{{OdataId: ptr("/redfish/v1/Chassis/1")}},
-			ManagedBy: &[]IdRef{{OdataId: ptr("/redfish/v1/Managers/1")}},
-		},
-		Boot: &Boot{
-			BootSourceOverrideEnabled: ptr(BootSourceOverrideEnabledContinuous),
-			BootSourceOverrideTarget:  ptr(None),
-			BootSourceOverrideTargetRedfishAllowableValues: &[]BootSource{
-				Pxe,
-				None,
+			Chassis: &[]IdRef{{OdataId: ptr("/redfish/v1/Chassis/1")}},
+			ManagedBy: &[]IdRef{
+				{OdataId: ptr("/redfish/v1/Managers/" + serviceManagerId)},
+				{OdataId: ptr("/redfish/v1/Managers/" + systemManagerId(systemId))},
 			},
 		},
+		Bios: &IdRef{
+			OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Bios", systemId)),
+		},
+		Boot: bootFor(r.state.system(s.UnifiPort)),
 		Actions: &ComputerSystemActions{
 			HashComputerSystemReset: &ComputerSystemReset{
-				ResetTypeRedfishAllowableValues: &[]ResetType{
-					ResetTypeOn,
-					ResetTypeForceOn,
-					ResetTypeForceOff,
-					ResetTypePowerCycle,
-				},
-				Target: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Actions/ComputerSystem.Reset", systemId)),
+				ResetTypeRedfishAllowableValues: &resetTypes,
+				Target:                          ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Actions/ComputerSystem.Reset", systemId)),
 			},
 		},
+		EthernetInterfaces: &IdRef{
+			OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/EthernetInterfaces", systemId)),
+		},
 		OdataId:   ptr(fmt.Sprintf("/redfish/v1/Systems/%s", systemId)),
 		OdataType: ptr("#ComputerSystem.v1_11_0.ComputerSystem"),
-		Name:      ptr(fmt.Sprintf("System %s", systemId)),
+		Name:      ptr(name),
 		Status: &Status{
 			State: ptr(StateEnabled),
 		},
 		UUID: ptr(s.MacAddress),
 	}
 
-	c.JSON(200, &resp)
+	model, hasModel := r.systemModel(s)
+
+	if hasModel {
+		if model.ProcessorCount > 0 {
+			resp.ProcessorSummary = &ProcessorSummary{
+				Count: ptr(model.ProcessorCount),
+			}
+		}
+		if model.MemoryGiB > 0 {
+			resp.MemorySummary = &MemorySummary{
+				TotalSystemMemoryGiB: ptr(model.MemoryGiB),
+			}
+		}
+	}
+
+	if s.Quarantined {
+		resp.Status = &Status{
+			State:  ptr(StateQuiesced),
+			Health: ptr(HealthWarning),
+		}
+	}
+
+	actions := &systemActions{
+		ComputerSystemActions: *resp.Actions,
+		Oem: &systemActionsOem{
+			Quarantine: &oemAction{
+				Target: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Actions/Oem/Unifi.Quarantine", systemId)),
+			},
+			ReleaseQuarantine: &oemAction{
+				Target: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Actions/Oem/Unifi.ReleaseQuarantine", systemId)),
+			},
+		},
+	}
+
+	if modes := r.networkModes(s); len(modes) > 0 {
+		allowable := slices.Sorted(maps.Keys(modes))
+		actions.Oem.SetNetworkMode = &setNetworkModeAction{
+			NetworkModeRedfishAllowableValues: &allowable,
+			Target:                            ptr(fmt.Sprintf("/redfish/v1/Systems/%s/Actions/Oem/Unifi.SetNetworkMode", systemId)),
+		}
+	}
+
+	out := computerSystem{
+		ComputerSystem: resp,
+		AssetTag:       ptr(state.AssetTag),
+		HostName:       ptr(state.HostName),
+		Actions:        actions,
+		LogServices:    &IdRef{OdataId: ptr(fmt.Sprintf("/redfish/v1/Systems/%s/LogServices", systemId))},
+		SecureBoot:     &IdRef{OdataId: ptr(secureBootPath(systemId))},
+		Oem: &systemOem{
+			Unifi: &systemUnifiOem{
+				DeviceMac:     ptr(s.DeviceMac),
+				ModelFamily:   ptr(r.modelFamily(s)),
+				NetworkMode:   networkMode,
+				PortProfileId: ptr(s.PortProfileID),
+				Quarantined:   ptr(s.Quarantined),
+				SwitchPort:    ptr(s.UnifiPort),
+			},
+		},
+	}
+
+	if hasModel {
+		out.Manufacturer = ptr(model.Manufacturer)
+		out.Model = ptr(model.Model)
+		if model.ProcessorModel != "" {
+			out.ProcessorSummary = &processorSummary{
+				CoreCount: ptr(model.CoreCount),
+				Model:     ptr(model.ProcessorModel),
+			}
+			if resp.ProcessorSummary != nil {
+				out.ProcessorSummary.ProcessorSummary = *resp.ProcessorSummary
+			}
+		}
+	}
+
+	c.JSON(200, &out)
 }
 
 // GetTask implements ServerInterface.
 func (r *RedfishServer) GetTask(c *gin.Context, taskId string) {
-	panic("unimplemented")
+	t, ok := r.tasks.get(taskId)
+	if !ok {
+		resourceNotFound(c, "Task", taskId)
+		return
+	}
+
+	c.JSON(200, taskResourceFor(t))
 }
 
 // GetTaskList implements ServerInterface.
 func (r *RedfishServer) GetTaskList(c *gin.Context) {
-	panic("unimplemented")
-}
 
-// GetVolumes implements ServerInterface.
-func (r *RedfishServer) GetVolumes(c *gin.Context, systemId string, storageControllerId string) {
-	panic("unimplemented")
-}
+	ids := make([]IdRef, 0)
+
+	for _, id := range r.tasks.ids() {
+		ids = append(ids, IdRef{
+			OdataId: ptr("/redfish/v1/TaskService/Tasks/" + id),
+		})
+	}
 
-// InsertVirtualMedia implements ServerInterface.
-func (r *RedfishServer) InsertVirtualMedia(c *gin.Context, managerId string, virtualMediaId string) {
-	panic("unimplemented")
+	tasks := Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#TaskCollection.TaskCollection"),
+		OdataType:         "#TaskCollection.TaskCollection",
+		Name:              ptr("Task Collection"),
+		OdataId:           "/redfish/v1/TaskService/Tasks",
+		MembersOdataCount: ptr(len(ids)),
+	}
+
+	c.JSON(200, &tasks)
 }
 
-// ListManagerVirtualMedia implements ServerInterface.
-func (r *RedfishServer) ListManagerVirtualMedia(c *gin.Context, managerId string) {
-	panic("unimplemented")
+// GetVolumes implements ServerInterface. Systems have no storage
+// controllers.
+func (r *RedfishServer) GetVolumes(c *gin.Context, systemId string, storageControllerId string) {
+	notImplemented(c)
 }
 
 // ListManagers implements ServerInterface.
 func (r *RedfishServer) ListManagers(c *gin.Context) {
-	panic("unimplemented")
+
+	err := r.refreshSystems(c.Request.Context())
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	ids := []IdRef{
+		{OdataId: ptr("/redfish/v1/Managers/" + serviceManagerId)},
+	}
+
+	for _, i := range r.systemIds() {
+		odataId := "/redfish/v1/Managers/" + systemManagerId(strconv.Itoa(i))
+		ids = append(ids, IdRef{
+			OdataId: &odataId,
+		})
+	}
+
+	managers := Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#ManagerCollection.ManagerCollection"),
+		OdataType:         "#ManagerCollection.ManagerCollection",
+		Name:              ptr("Manager Collection"),
+		OdataId:           "/redfish/v1/Managers",
+		MembersOdataCount: ptr(len(ids)),
+	}
+
+	c.JSON(200, &managers)
 }
 
 // ListSystems implements ServerInterface.
@@ -379,42 +823,61 @@ func (r *RedfishServer) ListSystems(c *gin.Context) {
 
 	ids := make([]IdRef, 0)
 
-	for i := range r.Systems {
+	for i := range r.systems() {
 		odataId := fmt.Sprintf("/redfish/v1/Systems/%d", i)
 		ids = append(ids, IdRef{
 			OdataId: &odataId,
 		})
 	}
 
-	systems := Collection{
-		Members:           &ids,
-		OdataContext:      ptr("/redfish/v1/$metadata#ComputerSystemCollection.ComputerSystemCollection"),
-		OdataType:         "#ComputerSystemCollection.ComputerSystemCollection",
-		Name:              ptr("Computer System Collection"),
-		OdataId:           "/redfish/v1/Systems",
-		MembersOdataCount: ptr(len(ids)),
+	systems := systemCollection{
+		Collection: Collection{
+			Members:           &ids,
+			OdataContext:      ptr("/redfish/v1/$metadata#ComputerSystemCollection.ComputerSystemCollection"),
+			OdataType:         "#ComputerSystemCollection.ComputerSystemCollection",
+			Name:              ptr("Computer System Collection"),
+			OdataId:           "/redfish/v1/Systems",
+			MembersOdataCount: ptr(len(ids)),
+		},
+		Actions: &systemCollectionActions{
+			Oem: &systemCollectionActionsOem{
+				Reset: &ComputerSystemReset{
+					ResetTypeRedfishAllowableValues: &resetTypes,
+					Target:                          ptr("/redfish/v1/Systems/Actions/Oem/Unifi.Reset"),
+				},
+			},
+		},
 	}
 
 	c.JSON(200, &systems)
 }
 
-// ResetIdrac implements ServerInterface.
+// ResetIdrac implements ServerInterface. There is no iDRAC to reset.
 func (r *RedfishServer) ResetIdrac(c *gin.Context) {
-	panic("unimplemented")
+	notImplemented(c)
 }
 
-// ResetSystem implements ServerInterface.
+// ResetSystem implements ServerInterface. The reset continues as a task
+// once the request has been validated.
 func (r *RedfishServer) ResetSystem(c *gin.Context, systemId string) {
 
-	req := ResetSystemJSONRequestBody{}
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(500, redfishError(err))
+	req := resetRequest{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.ResetType == nil {
+		invalidProperty(c, "ResetType", "ActionParameterMissing", "ComputerSystem.Reset", "ResetType")
+		return
+	}
+	if !slices.Contains(resetTypes, *req.ResetType) {
+		invalidProperty(c, "ResetType", "ActionParameterValueNotInList", string(*req.ResetType), "ResetType", "ComputerSystem.Reset")
 		return
 	}
 
 	systemIdInt, err := strconv.ParseInt(systemId, 10, 64)
 	if err != nil {
-		c.JSON(500, redfishError(err))
+		resourceNotFound(c, "ComputerSystem", systemId)
 		return
 	}
 
@@ -424,74 +887,91 @@ func (r *RedfishServer) ResetSystem(c *gin.Context, systemId string) {
 		return
 	}
 
-	sys, ok := r.Systems[int(systemIdInt)]
+	sys, ok := r.system(int(systemIdInt))
 	if !ok {
-		c.JSON(404, redfishError(fmt.Errorf("system not found")))
+		resourceNotFound(c, "ComputerSystem", systemId)
 		return
 	}
 
-	if sys.PoeMode == "off" {
-		_, err := r.updateDevicePort(c.Request.Context(), sys.UnifiPort, "auto")
-		if err != nil {
-			c.JSON(500, redfishError(err))
+	user, _ := contextUser(c)
+
+	if sys.PoeMode == "off" && !req.Oem.poeBudgetOverride() {
+		if err := r.checkPoeBudget(c.Request.Context(), sys, 0); err != nil {
+			r.audit(user, sys, "Reset", string(*req.ResetType), err)
+			c.JSON(409, redfishError(err))
 			return
 		}
-		sys.PoeMode = "auto"
-	} else if *req.ResetType == ResetTypePowerCycle {
-		_, err := r.client.ExecuteCmd(c.Request.Context(), r.Config.UnifiSite, "devmgr", unifi.Cmd{
+	}
+
+	target := fmt.Sprintf("/redfish/v1/Systems/%s/Actions/ComputerSystem.Reset", systemId)
+	t := r.startTask(fmt.Sprintf("%s reset of system %s", *req.ResetType, systemId), target, func(ctx context.Context, _ *taskProgress) error {
+		return r.resetSystem(ctx, user, sys, *req.ResetType)
+	})
+
+	acceptTask(c, t)
+}
+
+// resetSystem writes the staged BIOS settings of a system into its firmware
+// and resets it through its switch port on behalf of user. Systems without
+// PoE are powered on whatever the reset type.
+func (r *RedfishServer) resetSystem(ctx context.Context, user string, sys RedfishSystem, resetType ResetType) (err error) {
+	defer func() {
+		r.audit(user, sys, "Reset", string(resetType), err)
+	}()
+
+	// Staged BIOS settings take effect with the boot following the reset,
+	// so they are written before it and staged again if it fails.
+	applied, err := r.applyBiosSettings(user, sys)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, r.restoreBiosSettings(sys, applied))
+		}
+	}()
+
+	if sys.PoeMode == "off" {
+		_, err := r.updateDevicePort(ctx, sys.UnifiPort, "auto")
+		return err
+	}
+
+	switch resetType {
+	case ResetTypePowerCycle:
+		_, err := r.client.ExecuteCmd(ctx, r.Config.UnifiSite, "devmgr", unifi.Cmd{
 			Command: "power-cycle",
 			MAC:     sys.DeviceMac,
 			PortIDX: ptr(sys.UnifiPort),
 		})
-		if err != nil {
-			c.JSON(500, redfishError(err))
-			return
-		}
-		c.Status(204)
-		return
-	} else {
-		switch *req.ResetType {
-		case ResetTypeOn:
-			_, err := r.updateDevicePort(c.Request.Context(), sys.UnifiPort, "auto")
-			if err != nil {
-				c.JSON(500, redfishError(err))
-				return
-			}
-			c.Status(204)
-			return
-		case ResetTypeForceOn:
-			_, err := r.updateDevicePort(c.Request.Context(), sys.UnifiPort, "auto")
-			if err != nil {
-				c.JSON(500, redfishError(err))
-				return
-			}
-			c.Status(204)
-			return
-		case ResetTypeForceOff:
-			_, err := r.updateDevicePort(c.Request.Context(), sys.UnifiPort, "off")
-			if err != nil {
-				c.JSON(500, redfishError(err))
-				return
-			}
-			c.Status(204)
-			return
+		if err == nil {
+			// The PoE mode is left as is, so the monitor cannot see the
+			// transition.
+			r.publishPowerState(sys.UnifiPort, string(PoweringOn))
 		}
+		return err
+	case ResetTypeOn, ResetTypeForceOn:
+		_, err := r.updateDevicePort(ctx, sys.UnifiPort, "auto")
+		return err
+	case ResetTypeForceOff:
+		_, err := r.updateDevicePort(ctx, sys.UnifiPort, "off")
+		return err
 	}
+
+	return nil
 }
 
 // SetSystem implements ServerInterface.
 func (r *RedfishServer) SetSystem(c *gin.Context, systemId string) {
 
-	req := SetSystemJSONRequestBody{}
+	req := setSystemRequest{}
 
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(500, redfishError(err))
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	systemIdInt, err := strconv.ParseInt(systemId, 10, 64)
 	if err != nil {
-		c.JSON(500, redfishError(err))
+		resourceNotFound(c, "ComputerSystem", systemId)
 		return
 	}
 
@@ -501,36 +981,96 @@ func (r *RedfishServer) SetSystem(c *gin.Context, systemId string) {
 		return
 	}
 
-	sys, ok := r.Systems[int(systemIdInt)]
+	sys, ok := r.system(int(systemIdInt))
 	if !ok {
-		c.JSON(404, redfishError(fmt.Errorf("system not found")))
+		resourceNotFound(c, "ComputerSystem", systemId)
 		return
 	}
 
+	// The body is validated as a whole before anything is changed.
+	if req.PowerState != nil && !slices.Contains([]PowerState{On, Off, PoweringOn, PoweringOff}, *req.PowerState) {
+		invalidProperty(c, "PowerState", "PropertyValueNotInList", string(*req.PowerState), "PowerState")
+		return
+	}
+
+	var boot systemState
+	if req.Boot != nil {
+		boot = r.state.system(sys.UnifiPort)
+		if err := applyBoot(&boot, req.Boot); err != nil {
+			c.JSON(400, redfishError(err))
+			return
+		}
+	}
+
+	user, _ := contextUser(c)
+
 	poeMode := req.PowerState.GetPoeMode()
 
 	if poeMode != "" && poeMode != sys.PoeMode {
 
+		if sys.PoeMode == "off" && !req.Oem.poeBudgetOverride() {
+			if err := r.checkPoeBudget(c.Request.Context(), sys, 0); err != nil {
+				r.audit(user, sys, "SetPowerState", string(*req.PowerState), err)
+				c.JSON(409, redfishError(err))
+				return
+			}
+		}
+
 		sys.PoeMode = poeMode
 
 		_, err := r.updateDevicePort(c.Request.Context(), sys.UnifiPort, sys.PoeMode)
+		r.audit(user, sys, "SetPowerState", string(*req.PowerState), err)
 		if err != nil {
 			c.JSON(500, redfishError(err))
 			return
 		}
 	}
 
-	r.Systems[int(systemIdInt)] = sys
+	r.putSystem(sys)
 
-	c.JSON(204, nil)
-}
+	if req.Boot != nil {
+		err := r.state.updateSystem(sys.UnifiPort, func(st *systemState) {
+			st.BootSourceOverrideTarget = boot.BootSourceOverrideTarget
+			st.BootSourceOverrideEnabled = boot.BootSourceOverrideEnabled
+		})
+		r.audit(user, sys, "SetBootOverride", strings.TrimSpace(boot.BootSourceOverrideTarget+" "+boot.BootSourceOverrideEnabled), err)
+		if err != nil {
+			c.JSON(500, redfishError(err))
+			return
+		}
+	}
 
-// UpdateService implements ServerInterface.
-func (r *RedfishServer) UpdateService(c *gin.Context) {
-	panic("unimplemented")
-}
+	if req.Name != nil || req.HostName != nil || req.AssetTag != nil {
+		rename := func(st *systemState) {
+			if req.Name != nil {
+				st.Name = *req.Name
+			}
+			if req.HostName != nil {
+				st.HostName = *req.HostName
+			}
+			if req.AssetTag != nil {
+				st.AssetTag = *req.AssetTag
+			}
+		}
 
-// UpdateServiceSimpleUpdate implements ServerInterface.
-func (r *RedfishServer) UpdateServiceSimpleUpdate(c *gin.Context) {
-	panic("unimplemented")
+		// The controller is updated first so a failed sync leaves the local
+		// state unchanged.
+		if sys.MacAddress != "" {
+			state := r.state.system(sys.UnifiPort)
+			rename(&state)
+			err := r.syncClientName(c.Request.Context(), sys, state)
+			r.audit(user, sys, "SetName", state.Name, err)
+			if err != nil {
+				c.JSON(500, redfishError(err))
+				return
+			}
+		}
+
+		if err := r.state.updateSystem(sys.UnifiPort, rename); err != nil {
+			c.JSON(500, redfishError(err))
+			return
+		}
+	}
+
+	c.JSON(204, nil)
 }
@@ -0,0 +1,450 @@
+package redfish
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// authTokenHeader carries the token of a Redfish session.
+	authTokenHeader = "X-Auth-Token"
+
+	// userContextKey holds the name of the authenticated user in the gin
+	// context.
+	userContextKey = "redfish.user"
+
+	defaultSessionTimeout = 30 * time.Minute
+	minSessionTimeout     = 30 * time.Second
+	maxSessionTimeout     = 24 * time.Hour
+)
+
+// session is a Redfish login session authenticated by its token.
+type session struct {
+	Id       string
+	Token    string
+	UserName string
+	Created  time.Time
+	LastUsed time.Time
+}
+
+// sessionStore holds the open sessions in memory. Sessions expire once they
+// have been idle for longer than the timeout.
+type sessionStore struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	sessions map[string]*session
+}
+
+func newSessionStore(timeout time.Duration) *sessionStore {
+	if timeout <= 0 {
+		timeout = defaultSessionTimeout
+	}
+	return &sessionStore{
+		timeout:  timeout,
+		sessions: make(map[string]*session),
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// expire drops idle sessions. The caller must hold mu.
+func (s *sessionStore) expire(now time.Time) {
+	for id, sess := range s.sessions {
+		if now.Sub(sess.LastUsed) > s.timeout {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+func (s *sessionStore) create(userName string) (session, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return session{}, err
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return session{}, err
+	}
+
+	now := time.Now()
+	sess := &session{
+		Id:       id,
+		Token:    token,
+		UserName: userName,
+		Created:  now,
+		LastUsed: now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(now)
+	s.sessions[id] = sess
+
+	return *sess, nil
+}
+
+// authenticate returns the session holding token and marks it as used.
+func (s *sessionStore) authenticate(token string) (session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	for _, sess := range s.sessions {
+		if subtle.ConstantTimeCompare([]byte(sess.Token), []byte(token)) == 1 {
+			sess.LastUsed = now
+			return *sess, true
+		}
+	}
+
+	return session{}, false
+}
+
+func (s *sessionStore) get(id string) (session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return session{}, false
+	}
+	return *sess, true
+}
+
+func (s *sessionStore) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (s *sessionStore) delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return false
+	}
+	delete(s.sessions, id)
+	return true
+}
+
+func (s *sessionStore) getTimeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.timeout
+}
+
+func (s *sessionStore) setTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timeout = timeout
+}
+
+// sessionResource is the Redfish Session resource.
+type sessionResource struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	CreatedTime *string `json:"CreatedTime,omitempty"`
+	Id          *string `json:"Id,omitempty"`
+	Name        *string `json:"Name,omitempty"`
+	Password    *string `json:"Password,omitempty"`
+	UserName    *string `json:"UserName,omitempty"`
+}
+
+// sessionService is the Redfish SessionService resource.
+type sessionService struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	Id             *string `json:"Id,omitempty"`
+	Name           *string `json:"Name,omitempty"`
+	ServiceEnabled *bool   `json:"ServiceEnabled,omitempty"`
+	SessionTimeout *int    `json:"SessionTimeout,omitempty"`
+	Sessions       *IdRef  `json:"Sessions,omitempty"`
+	Status         *Status `json:"Status,omitempty"`
+}
+
+// serviceRoot extends the generated Root with the links to the services
+// served by this implementation.
+type serviceRoot struct {
+	Root
+
+	AccountService     *IdRef            `json:"AccountService,omitempty"`
+	CertificateService *IdRef            `json:"CertificateService,omitempty"`
+	EventService       *IdRef            `json:"EventService,omitempty"`
+	Links              *serviceRootLinks `json:"Links,omitempty"`
+	Registries         *IdRef            `json:"Registries,omitempty"`
+	SessionService     *IdRef            `json:"SessionService,omitempty"`
+	Tasks              *IdRef            `json:"Tasks,omitempty"`
+	UpdateService      *IdRef            `json:"UpdateService,omitempty"`
+}
+
+type serviceRootLinks struct {
+	Sessions *IdRef `json:"Sessions,omitempty"`
+}
+
+func sessionResourceFor(sess session) sessionResource {
+	return sessionResource{
+		OdataId:     ptr("/redfish/v1/SessionService/Sessions/" + sess.Id),
+		OdataType:   ptr("#Session.v1_4_0.Session"),
+		Id:          ptr(sess.Id),
+		Name:        ptr("User Session"),
+		UserName:    ptr(sess.UserName),
+		CreatedTime: ptr(sess.Created.Format(time.RFC3339)),
+	}
+}
+
+// authEnabled reports whether credentials are configured or accounts exist.
+// Requests are not authenticated without them.
+func (r *RedfishServer) authEnabled() bool {
+	return r.Config.AuthUsername != "" || r.accounts.len() > 0
+}
+
+// checkCredentials returns the role of the user if the user name and
+// password match the configured credentials or an enabled account.
+func (r *RedfishServer) checkCredentials(userName, password string) (string, bool) {
+	if r.Config.AuthUsername != "" {
+		userOk := subtle.ConstantTimeCompare([]byte(userName), []byte(r.Config.AuthUsername)) == 1
+		passOk := subtle.ConstantTimeCompare([]byte(password), []byte(r.Config.AuthPassword)) == 1
+		if userOk {
+			return RoleAdministrator, passOk
+		}
+	}
+
+	a, ok := r.accounts.get(userName)
+	if !ok || !a.Enabled {
+		return "", false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) != nil {
+		return "", false
+	}
+
+	return a.Role, true
+}
+
+// unauthenticated reports whether a route is served without credentials: the
+// service root, login and the health check.
+func unauthenticated(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/redfish/v1", "/health":
+		return c.Request.Method == "GET"
+	case "/redfish/v1/SessionService/Sessions":
+		return c.Request.Method == "POST"
+	}
+	return false
+}
+
+// Authenticate is a gin middleware requiring a session token in X-Auth-Token
+// or HTTP Basic credentials on every request but the service root, login and
+// the health check, and checking the role of the user holds the privilege the
+// request needs.
+func (r *RedfishServer) Authenticate(c *gin.Context) {
+	if !r.authEnabled() {
+		c.Next()
+		return
+	}
+
+	userName, role, ok := r.credentials(c)
+	if ok {
+		c.Set(userContextKey, userName)
+		c.Set(roleContextKey, role)
+	}
+
+	// Routes served without credentials still tell authenticated callers
+	// apart, invalid credentials are ignored.
+	if unauthenticated(c) {
+		c.Next()
+		return
+	}
+
+	if ok {
+		if r.authorize(c, role) {
+			c.Next()
+		}
+		return
+	}
+
+	c.Header("WWW-Authenticate", `Basic realm="Redfish"`)
+	c.AbortWithStatusJSON(401, redfishErrorCode(baseRegistry+"NoValidSession", fmt.Errorf("authentication required")))
+}
+
+// credentials returns the user and role of the session token or HTTP Basic
+// credentials of a request, if valid.
+func (r *RedfishServer) credentials(c *gin.Context) (userName, role string, ok bool) {
+	if token := c.GetHeader(authTokenHeader); token != "" {
+		sess, found := r.sessions.authenticate(token)
+		if !found {
+			return "", "", false
+		}
+		// The role is looked up on every request so changes to the account
+		// apply to its open sessions.
+		role, ok = r.userRole(sess.UserName)
+		return sess.UserName, role, ok
+	}
+
+	if user, password, basic := c.Request.BasicAuth(); basic {
+		role, ok = r.checkCredentials(user, password)
+		return user, role, ok
+	}
+
+	return "", "", false
+}
+
+// GetSessionService reports the session timeout and links the sessions.
+func (r *RedfishServer) GetSessionService(c *gin.Context) {
+	resp := sessionService{
+		OdataId:        ptr("/redfish/v1/SessionService"),
+		OdataType:      ptr("#SessionService.v1_1_8.SessionService"),
+		Id:             ptr("SessionService"),
+		Name:           ptr("Session Service"),
+		ServiceEnabled: ptr(r.authEnabled()),
+		SessionTimeout: ptr(int(r.sessions.getTimeout().Seconds())),
+		Sessions:       &IdRef{OdataId: ptr("/redfish/v1/SessionService/Sessions")},
+		Status: &Status{
+			State:  ptr(StateEnabled),
+			Health: ptr(HealthOK),
+		},
+	}
+
+	c.JSON(200, &resp)
+}
+
+// SetSessionService changes the idle timeout of sessions.
+func (r *RedfishServer) SetSessionService(c *gin.Context) {
+	req := sessionService{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.SessionTimeout != nil {
+		timeout := time.Duration(*req.SessionTimeout) * time.Second
+		if timeout < minSessionTimeout || timeout > maxSessionTimeout {
+			invalidProperty(c, "SessionTimeout", "PropertyValueOutOfRange", strconv.Itoa(int(*req.SessionTimeout)), "SessionTimeout")
+			return
+		}
+		r.sessions.setTimeout(timeout)
+	}
+
+	r.GetSessionService(c)
+}
+
+// ListSessions lists the open sessions.
+func (r *RedfishServer) ListSessions(c *gin.Context) {
+	ids := []IdRef{}
+	for _, id := range r.sessions.ids() {
+		ids = append(ids, IdRef{OdataId: ptr("/redfish/v1/SessionService/Sessions/" + id)})
+	}
+
+	c.JSON(200, &Collection{
+		Members:           &ids,
+		OdataContext:      ptr("/redfish/v1/$metadata#SessionCollection.SessionCollection"),
+		OdataType:         "#SessionCollection.SessionCollection",
+		Name:              ptr("Session Collection"),
+		OdataId:           "/redfish/v1/SessionService/Sessions",
+		MembersOdataCount: ptr(len(ids)),
+	})
+}
+
+// CreateSession logs in with a user name and password and returns the session
+// token in the X-Auth-Token header.
+func (r *RedfishServer) CreateSession(c *gin.Context) {
+	req := sessionResource{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var missing []string
+	if req.UserName == nil {
+		missing = append(missing, "UserName")
+	}
+	if req.Password == nil {
+		missing = append(missing, "Password")
+	}
+	if len(missing) > 0 {
+		propertyMissing(c, missing...)
+		return
+	}
+
+	if _, ok := r.checkCredentials(*req.UserName, *req.Password); r.authEnabled() && !ok {
+		c.JSON(401, redfishErrorCode(baseRegistry+"NoValidSession", fmt.Errorf("invalid credentials")))
+		return
+	}
+
+	sess, err := r.sessions.create(*req.UserName)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	resp := sessionResourceFor(sess)
+
+	c.Header(authTokenHeader, sess.Token)
+	c.Header("Location", *resp.OdataId)
+	c.JSON(201, &resp)
+}
+
+// GetSession reports an open session.
+func (r *RedfishServer) GetSession(c *gin.Context) {
+	sess, ok := r.sessions.get(c.Param("SessionId"))
+	if !ok {
+		resourceNotFound(c, "Session", c.Param("SessionId"))
+		return
+	}
+
+	resp := sessionResourceFor(sess)
+
+	c.JSON(200, &resp)
+}
+
+// DeleteSession logs out of a session. Users without ConfigureManager may
+// only delete their own sessions.
+func (r *RedfishServer) DeleteSession(c *gin.Context) {
+	id := c.Param("SessionId")
+
+	sess, ok := r.sessions.get(id)
+	if !ok {
+		resourceNotFound(c, "Session", c.Param("SessionId"))
+		return
+	}
+
+	userName, role := contextUser(c)
+	if r.authEnabled() && sess.UserName != userName && !hasPrivilege(role, PrivilegeConfigureManager) {
+		c.JSON(403, redfishErrorCode(baseRegistry+"InsufficientPrivilege", fmt.Errorf("only your own sessions can be deleted")))
+		return
+	}
+
+	r.sessions.delete(id)
+
+	c.Status(204)
+}
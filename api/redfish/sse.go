@@ -0,0 +1,200 @@
+package redfish
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	serverSentEventUri = "/redfish/v1/EventService/SSE"
+
+	// sseKeepAlive is how often an idle stream is sent a comment so proxies
+	// do not close it.
+	sseKeepAlive = 15 * time.Second
+)
+
+// sseFilterProperties are the event properties $filter can test.
+var sseFilterProperties = []string{"EventType", "MessageId", "OriginResource", "RegistryPrefix", "ResourceType"}
+
+// sseCondition tests a single property of an event for equality.
+type sseCondition struct {
+	Property string
+	Value    string
+}
+
+// sseFilter is a $filter expression in disjunctive normal form: an event
+// matches if all conditions of any term hold.
+type sseFilter [][]sseCondition
+
+// parseSSEFilter parses a $filter made of `eq` comparisons joined by `and`
+// and `or`, such as
+// `OriginResource eq '/redfish/v1/Systems/1' or EventType eq 'Alert'`.
+func parseSSEFilter(expr string) (sseFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	var filter sseFilter
+	for _, term := range strings.Split(expr, " or ") {
+		var conds []sseCondition
+		for _, cond := range strings.Split(term, " and ") {
+			prop, value, ok := strings.Cut(strings.TrimSpace(cond), " eq ")
+			if !ok {
+				return nil, fmt.Errorf("unsupported $filter condition %q", strings.TrimSpace(cond))
+			}
+
+			prop = strings.TrimSpace(prop)
+			if !slices.Contains(sseFilterProperties, prop) {
+				return nil, fmt.Errorf("$filter property must be one of %v", sseFilterProperties)
+			}
+
+			value = strings.Trim(strings.TrimSpace(value), "'")
+			conds = append(conds, sseCondition{Property: prop, Value: value})
+		}
+		filter = append(filter, conds)
+	}
+
+	return filter, nil
+}
+
+func (f sseFilter) matches(ev eventRecord) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	for _, term := range f {
+		ok := true
+		for _, cond := range term {
+			if eventProperty(ev, cond.Property) != cond.Value {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventProperty returns the value of an event property $filter can test.
+func eventProperty(ev eventRecord, property string) string {
+	origin := ""
+	if ev.OriginOfCondition != nil && ev.OriginOfCondition.OdataId != nil {
+		origin = *ev.OriginOfCondition.OdataId
+	}
+
+	switch property {
+	case "EventType":
+		return ev.EventType
+	case "MessageId":
+		return ev.MessageId
+	case "OriginResource":
+		return origin
+	case "RegistryPrefix":
+		prefix, _, _ := strings.Cut(ev.MessageId, ".")
+		return prefix
+	case "ResourceType":
+		return resourceType(origin)
+	}
+
+	return ""
+}
+
+// resourceType returns the type of the resource at path.
+func resourceType(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/redfish/v1/Systems/"):
+		return "ComputerSystem"
+	case strings.HasPrefix(path, "/redfish/v1/Managers/"):
+		return "Manager"
+	case strings.HasPrefix(path, "/redfish/v1/TaskService/Tasks/"):
+		return "Task"
+	}
+	return ""
+}
+
+// sseListener is an open event stream.
+type sseListener struct {
+	filter sseFilter
+	queue  chan eventRecord
+}
+
+func (s *eventService) listen(filter sseFilter) *sseListener {
+	l := &sseListener{
+		filter: filter,
+		queue:  make(chan eventRecord, subscriptionQueueSize),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listeners == nil {
+		s.listeners = make(map[*sseListener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+
+	return l
+}
+
+func (s *eventService) unlisten(l *sseListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.listeners, l)
+}
+
+// StreamEvents streams events as they are published to the client until it
+// disconnects.
+func (r *RedfishServer) StreamEvents(c *gin.Context) {
+	filter, err := parseSSEFilter(c.Query("$filter"))
+	if err != nil {
+		c.JSON(400, redfishErrorCode(baseRegistry+"QueryParameterValueFormatError", err, c.Query("$filter"), "$filter"))
+		return
+	}
+
+	l := r.events.listen(filter)
+	defer r.events.unlisten(l)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(200)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case ev := <-l.queue:
+			b, err := json.Marshal(event{
+				OdataType: "#Event.v1_4_0.Event",
+				Events:    []eventRecord{ev},
+				Id:        ev.EventId,
+				Name:      "Event",
+			})
+			if err != nil {
+				log.Printf("events: encoding event %s: %v", ev.EventId, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\ndata: %s\n\n", ev.EventId, b); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
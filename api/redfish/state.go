@@ -0,0 +1,120 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// systemState holds the properties of a system that are set through the API
+// and persisted across restarts.
+type systemState struct {
+	Name     string `json:"name,omitempty"`
+	HostName string `json:"host_name,omitempty"`
+	AssetTag string `json:"asset_tag,omitempty"`
+
+	BootSourceOverrideTarget  string `json:"boot_source_override_target,omitempty"`
+	BootSourceOverrideEnabled string `json:"boot_source_override_enabled,omitempty"`
+
+	// BiosSettings holds the BIOS attributes staged for the next reset.
+	BiosSettings map[string]any `json:"bios_settings,omitempty"`
+
+	// VirtualMedia is the media inserted into the virtual drive, if any.
+	VirtualMedia *virtualMediaState `json:"virtual_media,omitempty"`
+
+	// QuarantineProfileID is the port profile a quarantined system is
+	// released to, noPortProfile if its port had none.
+	QuarantineProfileID string `json:"quarantine_profile_id,omitempty"`
+}
+
+// stateStore persists systemState per switch port to a JSON file. An empty
+// path keeps the state in memory only.
+type stateStore struct {
+	mu   sync.Mutex
+	path string
+
+	Systems map[string]systemState `json:"systems"`
+}
+
+func loadState(path string) (*stateStore, error) {
+	s := &stateStore{
+		path:    path,
+		Systems: make(map[string]systemState),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+
+	if s.Systems == nil {
+		s.Systems = make(map[string]systemState)
+	}
+
+	return s, nil
+}
+
+// save writes the state to a temporary file and renames it over the
+// previous one. The caller must hold mu.
+func (s *stateStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+func (s *stateStore) system(port int) systemState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Systems[strconv.Itoa(port)]
+}
+
+// updateSystem applies fn to the state of the system on port and persists
+// the result if it changed.
+func (s *stateStore) updateSystem(port int, fn func(*systemState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strconv.Itoa(port)
+	prev := s.Systems[key]
+	next := prev
+	fn(&next)
+
+	if reflect.DeepEqual(next, prev) {
+		return nil
+	}
+
+	s.Systems[key] = next
+
+	return s.save()
+}
@@ -0,0 +1,211 @@
+package redfish
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// computerSystem extends the generated ComputerSystem with the OEM properties
+// and actions served by this implementation.
+type computerSystem struct {
+	ComputerSystem
+
+	Actions          *systemActions    `json:"Actions,omitempty"`
+	AssetTag         *string           `json:"AssetTag,omitempty"`
+	HostName         *string           `json:"HostName,omitempty"`
+	LogServices      *IdRef            `json:"LogServices,omitempty"`
+	Manufacturer     *string           `json:"Manufacturer,omitempty"`
+	Model            *string           `json:"Model,omitempty"`
+	Oem              *systemOem        `json:"Oem,omitempty"`
+	ProcessorSummary *processorSummary `json:"ProcessorSummary,omitempty"`
+	SecureBoot       *IdRef            `json:"SecureBoot,omitempty"`
+}
+
+type processorSummary struct {
+	ProcessorSummary
+
+	CoreCount *int    `json:"CoreCount,omitempty"`
+	Model     *string `json:"Model,omitempty"`
+}
+
+// setSystemRequest is the PATCH body accepted for a ComputerSystem.
+type setSystemRequest struct {
+	ComputerSystem
+
+	AssetTag *string     `json:"AssetTag,omitempty"`
+	HostName *string     `json:"HostName,omitempty"`
+	Oem      *oemRequest `json:"Oem,omitempty"`
+}
+
+type systemActions struct {
+	ComputerSystemActions
+
+	Oem *systemActionsOem `json:"Oem,omitempty"`
+}
+
+type systemActionsOem struct {
+	Quarantine        *oemAction            `json:"#Unifi.Quarantine,omitempty"`
+	ReleaseQuarantine *oemAction            `json:"#Unifi.ReleaseQuarantine,omitempty"`
+	SetNetworkMode    *setNetworkModeAction `json:"#Unifi.SetNetworkMode,omitempty"`
+}
+
+type oemAction struct {
+	// Target The unique identifier for a resource.
+	Target *string `json:"target,omitempty"`
+}
+
+type setNetworkModeAction struct {
+	NetworkModeRedfishAllowableValues *[]string `json:"NetworkMode@Redfish.AllowableValues,omitempty"`
+
+	// Target The unique identifier for a resource.
+	Target *string `json:"target,omitempty"`
+}
+
+type systemOem struct {
+	Unifi *systemUnifiOem `json:"Unifi,omitempty"`
+}
+
+type systemUnifiOem struct {
+	DeviceMac     *string `json:"DeviceMac,omitempty"`
+	ModelFamily   *string `json:"ModelFamily,omitempty"`
+	NetworkMode   *string `json:"NetworkMode,omitempty"`
+	PortProfileId *string `json:"PortProfileId,omitempty"`
+	Quarantined   *bool   `json:"Quarantined,omitempty"`
+	SwitchPort    *int    `json:"SwitchPort,omitempty"`
+}
+
+// lookupSystem refreshes the systems and returns the one addressed by
+// systemId, writing an error response if it cannot be found.
+func (r *RedfishServer) lookupSystem(c *gin.Context, systemId string) (sys RedfishSystem, ok bool) {
+	systemIdInt, err := strconv.ParseInt(systemId, 10, 64)
+	if err != nil {
+		resourceNotFound(c, "ComputerSystem", systemId)
+		return
+	}
+
+	if err := r.refreshSystems(c.Request.Context()); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	sys, ok = r.system(int(systemIdInt))
+	if !ok {
+		resourceNotFound(c, "ComputerSystem", systemId)
+	}
+
+	return
+}
+
+// resetTypes are the reset types systems support through their switch port.
+var resetTypes = []ResetType{
+	ResetTypeOn,
+	ResetTypeForceOn,
+	ResetTypeForceOff,
+	ResetTypePowerCycle,
+}
+
+// systemCollection extends the generated Collection with the actions on all
+// systems.
+type systemCollection struct {
+	Collection
+
+	Actions *systemCollectionActions `json:"Actions,omitempty"`
+}
+
+type systemCollectionActions struct {
+	Oem *systemCollectionActionsOem `json:"Oem,omitempty"`
+}
+
+type systemCollectionActionsOem struct {
+	Reset *ComputerSystemReset `json:"#Unifi.Reset,omitempty"`
+}
+
+// groupResetRequest is the body of the Unifi.Reset action, resetting the
+// systems listed in Targets one after another.
+type groupResetRequest struct {
+	ResetType *ResetType  `json:"ResetType,omitempty"`
+	Targets   []string    `json:"Targets,omitempty"`
+	Oem       *oemRequest `json:"Oem,omitempty"`
+}
+
+// ResetSystems resets a group of systems as a single task reporting the
+// progress of each reset. A failing reset does not stop the others; the task
+// completes with a warning instead.
+func (r *RedfishServer) ResetSystems(c *gin.Context) {
+	req := groupResetRequest{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.ResetType == nil {
+		invalidProperty(c, "ResetType", "ActionParameterMissing", "Unifi.Reset", "ResetType")
+		return
+	}
+	if !slices.Contains(resetTypes, *req.ResetType) {
+		invalidProperty(c, "ResetType", "ActionParameterValueNotInList", string(*req.ResetType), "ResetType", "Unifi.Reset")
+		return
+	}
+
+	if len(req.Targets) == 0 {
+		propertyMissing(c, "Targets")
+		return
+	}
+
+	if err := r.refreshSystems(c.Request.Context()); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	systems, err := r.systemTargets(req.Targets, actionTargets("Unifi.Reset"))
+	if err != nil {
+		c.JSON(400, redfishError(err))
+		return
+	}
+
+	resetType := *req.ResetType
+	override := req.Oem.poeBudgetOverride()
+	user, _ := contextUser(c)
+
+	t := r.startTask(fmt.Sprintf("%s reset of %d systems", resetType, len(systems)), "/redfish/v1/Systems/Actions/Oem/Unifi.Reset", func(ctx context.Context, p *taskProgress) error {
+		failed := 0
+		// pending is the expected draw of the systems powered on by this
+		// task, which the switch reports only once they have negotiated PoE.
+		pending := 0.0
+		for i, sys := range systems {
+			systemId := strconv.Itoa(sys.UnifiPort)
+
+			if sys.PoeMode == "off" && !override {
+				if err := r.checkPoeBudget(ctx, sys, pending); err != nil {
+					r.audit(user, sys, "Reset", string(resetType), err)
+					failed++
+					p.reportError(err, unifiRegistry+"SystemResetFailed", systemId, err.Error())
+					p.percent((i + 1) * 100 / len(systems))
+					continue
+				}
+			}
+
+			if err := r.resetSystem(ctx, user, sys, resetType); err != nil {
+				failed++
+				p.report(unifiRegistry+"SystemResetFailed", systemId, err.Error())
+			} else {
+				if sys.PoeMode == "off" {
+					pending += r.expectedDraw(sys)
+				}
+				p.report(unifiRegistry+"SystemReset", systemId)
+			}
+
+			p.percent((i + 1) * 100 / len(systems))
+		}
+
+		if failed == len(systems) {
+			return fmt.Errorf("no system was reset")
+		}
+		return nil
+	})
+
+	acceptTask(c, t)
+}
@@ -0,0 +1,399 @@
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultTaskRetention = time.Hour
+
+// maxCompletedTasks bounds the finished tasks retained. The oldest are
+// overwritten first, as CompletedTaskOverWritePolicy advertises.
+const maxCompletedTasks = 100
+
+// task is a long-running operation executed in the background.
+type task struct {
+	Id              string
+	Name            string
+	TargetUri       string
+	State           TaskState
+	Status          Health
+	Messages        []Message
+	PercentComplete int
+	StartTime       time.Time
+	EndTime         time.Time
+
+	// err is the error the operation failed with.
+	err error
+}
+
+func (t *task) finished() bool {
+	switch t.State {
+	case TaskStateCompleted, TaskStateException, TaskStateKilled, TaskStateCancelled:
+		return true
+	}
+	return false
+}
+
+// taskStore holds tasks in memory. Finished tasks are dropped once they are
+// older than the retention period, or the oldest of them once there are more
+// than maxCompletedTasks.
+type taskStore struct {
+	mu        sync.Mutex
+	retention time.Duration
+	next      int
+	tasks     map[string]*task
+}
+
+func newTaskStore(retention time.Duration) *taskStore {
+	if retention <= 0 {
+		retention = defaultTaskRetention
+	}
+	return &taskStore{
+		retention: retention,
+		tasks:     make(map[string]*task),
+	}
+}
+
+// expire drops finished tasks past the retention period and the oldest
+// finished tasks beyond maxCompletedTasks. The caller must hold mu.
+func (s *taskStore) expire(now time.Time) {
+	var finished []*task
+	for id, t := range s.tasks {
+		if !t.finished() {
+			continue
+		}
+		if now.Sub(t.EndTime) > s.retention {
+			delete(s.tasks, id)
+			continue
+		}
+		finished = append(finished, t)
+	}
+
+	if len(finished) <= maxCompletedTasks {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *task) int {
+		return a.EndTime.Compare(b.EndTime)
+	})
+	for _, t := range finished[:len(finished)-maxCompletedTasks] {
+		delete(s.tasks, t.Id)
+	}
+}
+
+func (s *taskStore) create(name, targetUri string) task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	s.next++
+	t := &task{
+		Id:        strconv.Itoa(s.next),
+		Name:      name,
+		TargetUri: targetUri,
+		State:     TaskStateNew,
+		Status:    HealthOK,
+		StartTime: now,
+	}
+	s.tasks[t.Id] = t
+
+	return t.copy()
+}
+
+func (t *task) copy() task {
+	c := *t
+	c.Messages = slices.Clone(t.Messages)
+	return c
+}
+
+func (s *taskStore) get(id string) (task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return task{}, false
+	}
+	return t.copy(), true
+}
+
+// ids returns the IDs of the retained tasks in the order they were created.
+func (s *taskStore) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+
+	ids := make([]int, 0, len(s.tasks))
+	for id := range s.tasks {
+		i, _ := strconv.Atoi(id)
+		ids = append(ids, i)
+	}
+	slices.Sort(ids)
+
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = strconv.Itoa(id)
+	}
+	return out
+}
+
+func (s *taskStore) update(id string, fn func(*task)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.tasks[id]; ok {
+		fn(t)
+	}
+}
+
+// taskProgress reports the progress of a running task.
+type taskProgress struct {
+	r  *RedfishServer
+	id string
+}
+
+// percent sets the completion percentage of the task.
+func (p *taskProgress) percent(n int) {
+	n = min(max(n, 0), 100)
+
+	changed := false
+	p.r.tasks.update(p.id, func(t *task) {
+		changed = t.PercentComplete != n
+		t.PercentComplete = n
+	})
+
+	if changed {
+		p.r.publishTask(p.id, taskMessage(HealthOK, taskEventRegistry+"TaskProgressChanged",
+			messageText(taskEventRegistry+"TaskProgressChanged", p.id, strconv.Itoa(n)), p.id, strconv.Itoa(n)))
+	}
+}
+
+// message appends a message to the task. Warnings and errors degrade the
+// status of the task without failing it.
+func (p *taskProgress) message(severity Health, messageId, message string, args ...string) {
+	m := taskMessage(severity, messageId, message, args...)
+
+	p.r.tasks.update(p.id, func(t *task) {
+		t.Messages = append(t.Messages, m)
+		if severity == HealthCritical || (severity == HealthWarning && t.Status == HealthOK) {
+			t.Status = severity
+		}
+	})
+
+	p.r.publishTask(p.id, m)
+}
+
+// report appends a registry message to the task, its text formatted from
+// args and its severity taken from the registry.
+func (p *taskProgress) report(messageId string, args ...string) {
+	m, _ := lookupMessage(messageId)
+	p.message(Health(m.Severity), messageId, formatMessage(m.Message, args), args...)
+}
+
+// reportError appends the registry message err carries to the task, or
+// messageId with args if it carries none.
+func (p *taskProgress) reportError(err error, messageId string, args ...string) {
+	var me *messageError
+	if errors.As(err, &me) {
+		p.report(me.messageId, me.args...)
+		return
+	}
+	p.report(messageId, args...)
+}
+
+// publishTask publishes a message of a task as an event originating from it.
+func (r *RedfishServer) publishTask(id string, m Message) {
+	ev := eventRecord{
+		EventType:         eventTypeStatusChange,
+		MessageId:         *m.MessageId,
+		Message:           *m.Message,
+		MessageSeverity:   *m.Severity,
+		OriginOfCondition: &IdRef{OdataId: ptr("/redfish/v1/TaskService/Tasks/" + id)},
+	}
+	if m.MessageArgs != nil {
+		ev.MessageArgs = *m.MessageArgs
+	}
+	r.events.publish(ev)
+}
+
+func taskMessage(severity Health, messageId, message string, args ...string) Message {
+	m := Message{
+		MessageId: ptr(messageId),
+		Message:   ptr(message),
+		Severity:  ptr(string(severity)),
+	}
+	if def, ok := lookupMessage(messageId); ok {
+		m.Resolution = ptr(def.Resolution)
+	}
+	if len(args) > 0 {
+		m.MessageArgs = &args
+	}
+	return m
+}
+
+func taskMonitorPath(id string) string {
+	return "/redfish/v1/TaskService/TaskMonitors/" + id
+}
+
+// startTask runs fn in the background, tracking it as a task targeting
+// targetUri.
+func (r *RedfishServer) startTask(name, targetUri string, fn func(ctx context.Context, p *taskProgress) error) task {
+	id := r.tasks.create(name, targetUri).Id
+	p := &taskProgress{r: r, id: id}
+
+	started := taskMessage(HealthOK, taskEventRegistry+"TaskStarted", messageText(taskEventRegistry+"TaskStarted", id), id)
+	r.tasks.update(id, func(t *task) {
+		t.State = TaskStateRunning
+		t.Messages = append(t.Messages, started)
+	})
+	r.publishTask(id, started)
+
+	go func() {
+		err := runTask(fn, p)
+
+		var done Message
+		r.tasks.update(id, func(t *task) {
+			t.EndTime = time.Now()
+			switch {
+			case err != nil:
+				t.State = TaskStateException
+				t.Status = HealthCritical
+				t.err = err
+				done = taskMessage(HealthCritical, taskEventRegistry+"TaskAborted", fmt.Sprintf("The task with Id '%s' has been aborted: %v", id, err), id)
+			case t.Status == HealthOK:
+				t.State = TaskStateCompleted
+				t.PercentComplete = 100
+				done = taskMessage(HealthOK, taskEventRegistry+"TaskCompletedOK", messageText(taskEventRegistry+"TaskCompletedOK", id), id)
+			default:
+				t.State = TaskStateCompleted
+				t.PercentComplete = 100
+				done = taskMessage(HealthWarning, taskEventRegistry+"TaskCompletedWarning", messageText(taskEventRegistry+"TaskCompletedWarning", id), id)
+			}
+			t.Messages = append(t.Messages, done)
+		})
+		r.publishTask(id, done)
+
+		if err != nil {
+			log.Printf("task: %s %s failed: %v", id, name, err)
+		}
+	}()
+
+	t, _ := r.tasks.get(id)
+	return t
+}
+
+// runTask runs fn, turning a panic into an error so a failing task does not
+// take the daemon down.
+func runTask(fn func(ctx context.Context, p *taskProgress) error, p *taskProgress) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return fn(context.Background(), p)
+}
+
+// acceptTask responds to a request whose operation continues as a task.
+func acceptTask(c *gin.Context, t task) {
+	c.Header("Location", taskMonitorPath(t.Id))
+	c.JSON(202, taskResourceFor(t))
+}
+
+// taskResource extends the generated Task with the properties served by
+// this implementation.
+type taskResource struct {
+	Task
+
+	PercentComplete *int `json:"PercentComplete,omitempty"`
+}
+
+func taskResourceFor(t task) *taskResource {
+	resp := &taskResource{
+		Task: Task{
+			OdataId:     ptr("/redfish/v1/TaskService/Tasks/" + t.Id),
+			OdataType:   ptr("#Task.v1_4_3.Task"),
+			Id:          ptr(t.Id),
+			Name:        ptr(t.Name),
+			Description: ptr(t.Name),
+			Messages:    &t.Messages,
+			StartTime:   ptr(t.StartTime.UTC()),
+			TaskState:   ptr(t.State),
+			TaskStatus:  ptr(t.Status),
+			Payload: &Payload{
+				TargetUri: ptr(t.TargetUri),
+			},
+		},
+		PercentComplete: ptr(t.PercentComplete),
+	}
+
+	if t.finished() {
+		resp.EndTime = ptr(t.EndTime.UTC().Format(time.RFC3339))
+	} else {
+		resp.TaskMonitor = ptr(taskMonitorPath(t.Id))
+	}
+
+	return resp
+}
+
+// taskService is the Redfish TaskService resource.
+type taskService struct {
+	OdataId   *string `json:"@odata.id,omitempty"`
+	OdataType *string `json:"@odata.type,omitempty"`
+
+	CompletedTaskOverWritePolicy    *string `json:"CompletedTaskOverWritePolicy,omitempty"`
+	DateTime                        *string `json:"DateTime,omitempty"`
+	Id                              *string `json:"Id,omitempty"`
+	LifeCycleEventOnTaskStateChange *bool   `json:"LifeCycleEventOnTaskStateChange,omitempty"`
+	Name                            *string `json:"Name,omitempty"`
+	ServiceEnabled                  *bool   `json:"ServiceEnabled,omitempty"`
+	Status                          *Status `json:"Status,omitempty"`
+	Tasks                           *IdRef  `json:"Tasks,omitempty"`
+}
+
+// GetTaskService reports the TaskService.
+func (r *RedfishServer) GetTaskService(c *gin.Context) {
+	c.JSON(200, &taskService{
+		OdataId:                         ptr("/redfish/v1/TaskService"),
+		OdataType:                       ptr("#TaskService.v1_2_0.TaskService"),
+		Id:                              ptr("TaskService"),
+		Name:                            ptr("Task Service"),
+		CompletedTaskOverWritePolicy:    ptr("Oldest"),
+		DateTime:                        ptr(time.Now().Format(time.RFC3339)),
+		LifeCycleEventOnTaskStateChange: ptr(true),
+		ServiceEnabled:                  ptr(true),
+		Status:                          &Status{State: ptr(StateEnabled)},
+		Tasks:                           &IdRef{OdataId: ptr("/redfish/v1/TaskService/Tasks")},
+	})
+}
+
+// GetTaskMonitor reports a running task with 202 Accepted and the outcome
+// of its operation once it has finished.
+func (r *RedfishServer) GetTaskMonitor(c *gin.Context) {
+	t, ok := r.tasks.get(c.Param("taskId"))
+	if !ok {
+		resourceNotFound(c, "Task", c.Param("taskId"))
+		return
+	}
+
+	switch {
+	case !t.finished():
+		acceptTask(c, t)
+	case t.err != nil:
+		c.JSON(500, redfishError(t.err))
+	default:
+		c.Status(204)
+	}
+}
@@ -0,0 +1,353 @@
+package redfish
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appkins-org/go-redfish-uefi/pkg/firmware"
+	"github.com/gin-gonic/gin"
+	"github.com/pin/tftp/v3"
+)
+
+// maxFirmwareImageSize bounds the size of a downloaded firmware image.
+// RPI_EFI.fd is a few MiB.
+const maxFirmwareImageSize = 32 << 20
+
+var errImageTooLarge = fmt.Errorf("image exceeds %d bytes", maxFirmwareImageSize)
+
+// limitedBuffer is a buffer failing writes past maxFirmwareImageSize.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxFirmwareImageSize {
+		return 0, errImageTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// imageURL returns the URL an image is downloaded from. An ImageURI without a
+// scheme takes it from the transfer protocol.
+func imageURL(imageURI string, protocol *TransferProtocolType) (*url.URL, error) {
+	if protocol != nil && !strings.Contains(imageURI, "://") {
+		imageURI = strings.ToLower(string(*protocol)) + "://" + imageURI
+	}
+
+	u, err := url.Parse(imageURI)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https", "tftp":
+	default:
+		return nil, fmt.Errorf("ImageURI must be an http, https or tftp URL")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("ImageURI has no host")
+	}
+	if protocol != nil && !strings.EqualFold(string(*protocol), u.Scheme) {
+		return nil, fmt.Errorf("TransferProtocolType %s does not match ImageURI", *protocol)
+	}
+
+	return u, nil
+}
+
+// fetchImage downloads an image over HTTP or TFTP.
+func fetchImage(ctx context.Context, u *url.URL) ([]byte, error) {
+	buf := &limitedBuffer{}
+
+	switch u.Scheme {
+	case "tftp":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "69")
+		}
+		client, err := tftp.NewClient(host)
+		if err != nil {
+			return nil, err
+		}
+		wt, err := client.Receive(strings.TrimPrefix(u.Path, "/"), "octet")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := wt.WriteTo(buf); err != nil {
+			return nil, err
+		}
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		client := &http.Client{Timeout: 5 * time.Minute}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("downloading %s: %s", u.Redacted(), resp.Status)
+		}
+		if _, err := io.Copy(buf, resp.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// installFirmware writes the firmware image b to path, carrying the variables
+// of the image read by installed across. It returns how many were migrated.
+func installFirmware(b []byte, path string, installed func() (*firmware.Image, error)) (int, error) {
+	img, err := firmware.Parse(b)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	prev, err := installed()
+	switch {
+	case err == nil:
+		img.CopyVariables(prev)
+		migrated = len(prev.Variables)
+	case !errors.Is(err, os.ErrNotExist):
+		return 0, fmt.Errorf("reading installed image: %w", err)
+	}
+
+	return migrated, img.WriteFile(path)
+}
+
+// installSystemFirmware installs the firmware image b as the own copy of a
+// system. Systems without one are migrated from the shared image they were
+// served so far.
+func (r *RedfishServer) installSystemFirmware(b []byte, sys RedfishSystem) (int, error) {
+	r.firmwareMu.Lock()
+	defer r.firmwareMu.Unlock()
+
+	path, err := r.systemFirmwarePath(sys)
+	if err != nil {
+		return 0, err
+	}
+
+	return installFirmware(b, path, func() (*firmware.Image, error) {
+		return r.readSystemFirmware(sys)
+	})
+}
+
+// actionTargets returns the error reported by systemTargets for a target of
+// action that is not a system.
+func actionTargets(action string) func(target string) error {
+	return func(target string) error {
+		return propertyError("Targets", "ActionParameterValueNotInList", target, "Targets", action)
+	}
+}
+
+// systemTargets returns the systems an action targets by URI, or the error
+// invalid returns for the first target that is not a system.
+func (r *RedfishServer) systemTargets(targets []string, invalid func(target string) error) ([]RedfishSystem, error) {
+	systems := make([]RedfishSystem, 0, len(targets))
+	for _, target := range targets {
+		id, ok := strings.CutPrefix(strings.TrimSuffix(target, "/"), "/redfish/v1/Systems/")
+		i, err := strconv.Atoi(id)
+		sys, found := r.system(i)
+		if !ok || err != nil || !found {
+			return nil, invalid(target)
+		}
+		systems = append(systems, sys)
+	}
+	return systems, nil
+}
+
+// ownFirmwareSystems returns the systems with their own copy of the firmware
+// image, which do not follow updates of the shared image.
+func (r *RedfishServer) ownFirmwareSystems() []RedfishSystem {
+	var systems []RedfishSystem
+	for _, sys := range r.systems() {
+		path, err := r.systemFirmwarePath(sys)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			systems = append(systems, sys)
+		}
+	}
+	return systems
+}
+
+// updateService extends the generated UpdateService with the properties
+// served by this implementation.
+type updateService struct {
+	UpdateService
+
+	MaxImageSizeBytes    *int64  `json:"MaxImageSizeBytes,omitempty"`
+	MultipartHttpPushUri *string `json:"MultipartHttpPushUri,omitempty"`
+}
+
+// UpdateService implements ServerInterface.
+func (r *RedfishServer) UpdateService(c *gin.Context) {
+	resp := updateService{UpdateService: UpdateService{
+		OdataId:     ptr("/redfish/v1/UpdateService"),
+		OdataType:   ptr("#UpdateService.v1_8_0.UpdateService"),
+		Id:          ptr("UpdateService"),
+		Name:        ptr("Update Service"),
+		Description: ptr("Installs UEFI firmware images served to systems"),
+		Actions: &UpdateServiceActions{
+			HashUpdateServiceSimpleUpdate: &VirtualMediaActionsVirtualMediaEjectMedia{
+				Target: ptr("/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate"),
+			},
+		},
+		FirmwareInventory: &FirmwareInventory{
+			OdataId: ptr("/redfish/v1/UpdateService/FirmwareInventory"),
+		},
+		ServiceEnabled: ptr(true),
+	}}
+
+	if r.repository.enabled() {
+		resp.HttpPushUri = ptr("/redfish/v1/UpdateService/FirmwareInventory")
+		resp.MultipartHttpPushUri = ptr("/redfish/v1/UpdateService/FirmwareInventory")
+		resp.MaxImageSizeBytes = ptr(r.repository.maxSize)
+	}
+
+	c.JSON(200, &resp)
+}
+
+// updateTargets returns the systems an update of targets installs to. No
+// targets means the shared image and every system with its own copy.
+func (r *RedfishServer) updateTargets(targets *[]string, invalid func(target string) error) (shared bool, systems []RedfishSystem, err error) {
+	if targets == nil || len(*targets) == 0 {
+		return true, r.ownFirmwareSystems(), nil
+	}
+
+	systems, err = r.systemTargets(*targets, invalid)
+	return false, systems, err
+}
+
+// startFirmwareUpdate installs the image returned by fetch to the shared
+// image if shared is set and to every system of systems, as a task on behalf
+// of user. source names the image in messages and the event log.
+func (r *RedfishServer) startFirmwareUpdate(user, source, targetUri string, fetch func(ctx context.Context) ([]byte, error), shared bool, systems []RedfishSystem) task {
+	return r.startTask("Firmware update from "+source, targetUri, func(ctx context.Context, p *taskProgress) error {
+		b, err := fetch(ctx)
+		if err != nil {
+			return fmt.Errorf("fetching image: %w", err)
+		}
+		if _, err := firmware.Parse(b); err != nil {
+			return fmt.Errorf("%s is not an RPi UEFI firmware image: %w", source, err)
+		}
+		p.percent(10)
+
+		steps := len(systems)
+		if shared {
+			steps++
+		}
+		done := 0
+		failed := 0
+
+		if shared {
+			path := filepath.Join(r.Config.TftpRootDirectory, firmwareImage)
+			r.firmwareMu.Lock()
+			migrated, err := installFirmware(b, path, func() (*firmware.Image, error) {
+				return firmware.ReadFile(path)
+			})
+			r.firmwareMu.Unlock()
+			if err != nil {
+				failed++
+				p.report(unifiRegistry+"FirmwareUpdateFailed", softwareInventoryPath("Shared-UEFI"), err.Error())
+			} else {
+				p.report(unifiRegistry+"FirmwareUpdated", softwareInventoryPath("Shared-UEFI"), strconv.Itoa(migrated))
+			}
+			log.Printf("update: shared %s from %s by %q: %v", firmwareImage, source, user, err)
+			done++
+			p.percent(10 + done*90/steps)
+		}
+
+		for _, sys := range systems {
+			systemId := strconv.Itoa(sys.UnifiPort)
+
+			migrated, err := r.installSystemFirmware(b, sys)
+			r.audit(user, sys, "UpdateFirmware", source, err)
+			if err != nil {
+				failed++
+				p.report(unifiRegistry+"FirmwareUpdateFailed", softwareInventoryPath(systemId+"-UEFI"), err.Error())
+			} else {
+				p.report(unifiRegistry+"FirmwareUpdated", softwareInventoryPath(systemId+"-UEFI"), strconv.Itoa(migrated))
+			}
+
+			done++
+			p.percent(10 + done*90/steps)
+		}
+
+		if steps > 0 && failed == steps {
+			return fmt.Errorf("no firmware image was updated")
+		}
+		return nil
+	})
+}
+
+// UpdateServiceSimpleUpdate implements ServerInterface. The image at
+// ImageURI is installed as RPI_EFI.fd of every target system, keeping the
+// variables of the image it replaces. Without targets the shared image and
+// every system with its own copy are updated. ImageURI may also name an
+// image of the firmware repository by its inventory URI. The update continues
+// as a task once the request has been validated.
+func (r *RedfishServer) UpdateServiceSimpleUpdate(c *gin.Context) {
+	req := SimpleUpdateRequestBody{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var source string
+	var fetch func(ctx context.Context) ([]byte, error)
+
+	if id, ok := strings.CutPrefix(req.ImageURI, repositoryInventoryPrefix); ok {
+		img, ok := r.repository.get(id)
+		if !ok {
+			invalidProperty(c, "ImageURI", "ActionParameterValueNotInList", req.ImageURI, "ImageURI", "UpdateService.SimpleUpdate")
+			return
+		}
+		source = req.ImageURI
+		fetch = func(context.Context) ([]byte, error) {
+			return os.ReadFile(r.repository.path(img.Id))
+		}
+	} else {
+		u, err := imageURL(req.ImageURI, req.TransferProtocolType)
+		if err != nil {
+			invalidProperty(c, "ImageURI", "ActionParameterValueFormatError", req.ImageURI, "ImageURI", "UpdateService.SimpleUpdate")
+			return
+		}
+		source = u.Redacted()
+		fetch = func(ctx context.Context) ([]byte, error) {
+			return fetchImage(ctx, u)
+		}
+	}
+
+	if err := r.refreshSystems(c.Request.Context()); err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	shared, systems, err := r.updateTargets(req.Targets, actionTargets("UpdateService.SimpleUpdate"))
+	if err != nil {
+		c.JSON(400, redfishError(err))
+		return
+	}
+
+	user, _ := contextUser(c)
+	t := r.startFirmwareUpdate(user, source, "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate", fetch, shared, systems)
+
+	acceptTask(c, t)
+}
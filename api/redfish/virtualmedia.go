@@ -0,0 +1,607 @@
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// virtualMediaId is the id of the single virtual drive of every system
+// manager.
+const virtualMediaId = "Cd"
+
+// virtualMediaScript is the iPXE script fetched over TFTP at startup. While
+// media is inserted and the system boots from Cd, it is generated to sanboot
+// the media.
+const virtualMediaScript = "autoexec.ipxe"
+
+// mediaPrefix prefixes the URLs media is served to systems on. The token
+// following it authorizes the request, as iPXE cannot authenticate.
+const mediaPrefix = "/media/"
+
+const defaultVirtualMediaMaxSize = 8 << 30
+
+// errMediaEjected is returned for downloads of media ejected meanwhile.
+var errMediaEjected = errors.New("virtual media was ejected")
+
+// mediaDownloads holds the cancel funcs of the running downloads of media
+// inserted with the Upload transfer method, by media token.
+type mediaDownloads struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// start returns the context of the download of the media with the given
+// token, canceled by cancel.
+func (d *mediaDownloads) start(ctx context.Context, token string) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancels == nil {
+		d.cancels = make(map[string]context.CancelFunc)
+	}
+	d.cancels[token] = cancel
+
+	return ctx
+}
+
+// cancel stops the download of the media with the given token, if running.
+func (d *mediaDownloads) cancel(token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cancel, ok := d.cancels[token]; ok {
+		cancel()
+		delete(d.cancels, token)
+	}
+}
+
+// virtualMediaState is the media inserted into the virtual drive of a system.
+type virtualMediaState struct {
+	Image                string `json:"image"`
+	ImageName            string `json:"image_name"`
+	TransferMethod       string `json:"transfer_method"`
+	TransferProtocolType string `json:"transfer_protocol_type,omitempty"`
+	WriteProtected       bool   `json:"write_protected"`
+	// Token names the media in the URLs it is served on.
+	Token string `json:"token"`
+}
+
+// mediaImageName returns the file name media is served as, taken from the
+// last element of its URL.
+func mediaImageName(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || strings.ContainsAny(name, `\`) {
+		return "image.iso"
+	}
+	return name
+}
+
+// mediaURL returns the URL a system fetches file of media from.
+func (r *RedfishServer) mediaURL(media *virtualMediaState, file string) string {
+	return strings.TrimSuffix(r.Config.VirtualMediaBaseURL, "/") + mediaPrefix + media.Token + "/" + url.PathEscape(file)
+}
+
+// mediaCachePath returns the path media transferred with the Upload method is
+// kept at, or an empty string if caching is disabled.
+func (r *RedfishServer) mediaCachePath(media *virtualMediaState) string {
+	if r.Config.VirtualMediaCacheDirectory == "" {
+		return ""
+	}
+	return filepath.Join(r.Config.VirtualMediaCacheDirectory, media.Token)
+}
+
+// sanbootScript returns the iPXE script booting media.
+func (r *RedfishServer) sanbootScript(port int, media *virtualMediaState) []byte {
+	return fmt.Appendf(nil, `#!ipxe
+echo Booting virtual media %s of system %d
+sanboot --no-describe %s || goto failed
+:failed
+echo Booting virtual media failed
+exit
+`, media.ImageName, port, r.mediaURL(media, media.ImageName))
+}
+
+// virtualMediaFor returns the VirtualMedia resource of the virtual drive of a
+// system.
+func virtualMediaFor(managerId string, media *virtualMediaState) *VirtualMedia {
+	base := "/redfish/v1/Managers/" + managerId + "/VirtualMedia/" + virtualMediaId
+
+	resp := &VirtualMedia{
+		OdataContext: ptr("/redfish/v1/$metadata#VirtualMedia.VirtualMedia"),
+		OdataId:      ptr(base),
+		OdataType:    ptr("#VirtualMedia.v1_3_0.VirtualMedia"),
+		Id:           ptr(virtualMediaId),
+		Name:         ptr("Virtual CD"),
+		Description:  ptr("Image booted through iPXE sanboot"),
+		MediaTypes:   &[]string{"CD", "DVD"},
+		ConnectedVia: ptr(NotConnected),
+		Inserted:     ptr(false),
+		Actions: &VirtualMediaActions{
+			HashVirtualMediaEjectMedia: &VirtualMediaActionsVirtualMediaEjectMedia{
+				Target: ptr(base + "/Actions/VirtualMedia.EjectMedia"),
+			},
+			HashVirtualMediaInsertMedia: &VirtualMediaActionsVirtualMediaEjectMedia{
+				Target: ptr(base + "/Actions/VirtualMedia.InsertMedia"),
+			},
+		},
+	}
+
+	if media != nil {
+		resp.ConnectedVia = ptr(URI)
+		resp.Image = ptr(media.Image)
+		resp.ImageName = ptr(media.ImageName)
+		resp.Inserted = ptr(true)
+		resp.WriteProtected = ptr(media.WriteProtected)
+		resp.TransferMethod = ptr(TransferMethod(media.TransferMethod))
+		if media.TransferProtocolType != "" {
+			resp.TransferProtocolType = ptr(TransferProtocolType(media.TransferProtocolType))
+		}
+	}
+
+	return resp
+}
+
+// lookupManagerSystem returns the system of a per-system manager, writing an
+// error response if it cannot be found.
+func (r *RedfishServer) lookupManagerSystem(c *gin.Context, managerId string) (RedfishSystem, bool) {
+	systemId, ok := parseSystemManagerId(managerId)
+	if !ok {
+		resourceNotFound(c, "Manager", managerId)
+		return RedfishSystem{}, false
+	}
+	return r.lookupSystem(c, strconv.Itoa(systemId))
+}
+
+// ListManagerVirtualMedia implements ServerInterface. Every system manager
+// has a single virtual drive.
+func (r *RedfishServer) ListManagerVirtualMedia(c *gin.Context, managerId string) {
+	if _, ok := r.lookupManagerSystem(c, managerId); !ok {
+		return
+	}
+
+	base := "/redfish/v1/Managers/" + managerId + "/VirtualMedia"
+
+	c.JSON(200, &Collection{
+		Members:           &[]IdRef{{OdataId: ptr(base + "/" + virtualMediaId)}},
+		OdataContext:      ptr("/redfish/v1/$metadata#VirtualMediaCollection.VirtualMediaCollection"),
+		OdataType:         "#VirtualMediaCollection.VirtualMediaCollection",
+		Name:              ptr("Virtual Media Collection"),
+		OdataId:           base,
+		MembersOdataCount: ptr(1),
+	})
+}
+
+// GetManagerVirtualMedia implements ServerInterface.
+func (r *RedfishServer) GetManagerVirtualMedia(c *gin.Context, managerId string, virtualMediaId string) {
+	sys, ok := r.lookupManagerSystem(c, managerId)
+	if !ok {
+		return
+	}
+	if !checkVirtualMediaId(c, virtualMediaId) {
+		return
+	}
+
+	c.JSON(200, virtualMediaFor(managerId, r.state.system(sys.UnifiPort).VirtualMedia))
+}
+
+func checkVirtualMediaId(c *gin.Context, id string) bool {
+	if id != virtualMediaId {
+		resourceNotFound(c, "VirtualMedia", id)
+		return false
+	}
+	return true
+}
+
+// InsertVirtualMedia implements ServerInterface. The image is served to the
+// system from mediaPrefix, proxied from Image with the Stream transfer method
+// or downloaded to the cache directory first with Upload, in which case the
+// download continues as a task. The system boots it once its boot override is
+// set to Cd.
+func (r *RedfishServer) InsertVirtualMedia(c *gin.Context, managerId string, virtualMediaId string) {
+	sys, ok := r.lookupManagerSystem(c, managerId)
+	if !ok {
+		return
+	}
+	if !checkVirtualMediaId(c, virtualMediaId) {
+		return
+	}
+
+	req := InsertMediaRequestBody{}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Inserted != nil && !*req.Inserted {
+		c.JSON(400, redfishErrorCode(baseRegistry+"PropertyValueNotInList", fmt.Errorf("Inserted must be true"), "false", "Inserted"))
+		return
+	}
+	if req.UserName != nil || req.Password != nil {
+		property := "UserName"
+		if req.UserName == nil {
+			property = "Password"
+		}
+		c.JSON(400, redfishErrorCode(baseRegistry+"PropertyNotWritable", fmt.Errorf("images requiring credentials are not supported"), property))
+		return
+	}
+
+	if req.WriteProtected != nil && !*req.WriteProtected {
+		c.JSON(400, redfishErrorCode(baseRegistry+"PropertyValueNotInList", fmt.Errorf("virtual media is always write protected"), "false", "WriteProtected"))
+		return
+	}
+
+	u, err := imageURL(req.Image, req.TransferProtocolType)
+	if err == nil && u.Scheme == "tftp" {
+		err = fmt.Errorf("Image must be an http or https URL")
+	}
+	if err != nil {
+		c.JSON(400, redfishErrorCode(baseRegistry+"PropertyValueFormatError", err, req.Image, "Image"))
+		return
+	}
+
+	method := Stream
+	if req.TransferMethod != nil {
+		method = *req.TransferMethod
+	}
+	switch method {
+	case Stream:
+	case Upload:
+		if r.Config.VirtualMediaCacheDirectory == "" {
+			c.JSON(400, redfishErrorCode(baseRegistry+"PropertyValueNotInList", fmt.Errorf("TransferMethod Upload requires a cache directory"), string(method), "TransferMethod"))
+			return
+		}
+	default:
+		c.JSON(400, redfishErrorCode(baseRegistry+"PropertyValueNotInList", fmt.Errorf("unsupported TransferMethod %q", method), string(method), "TransferMethod"))
+		return
+	}
+
+	token, err := randomHex(16)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	media := &virtualMediaState{
+		Image:          u.String(),
+		ImageName:      mediaImageName(u),
+		TransferMethod: string(method),
+		WriteProtected: true,
+		Token:          token,
+	}
+	if req.TransferProtocolType != nil {
+		media.TransferProtocolType = string(*req.TransferProtocolType)
+	}
+
+	inserted := false
+	err = r.state.updateSystem(sys.UnifiPort, func(st *systemState) {
+		if st.VirtualMedia != nil {
+			inserted = true
+			return
+		}
+		st.VirtualMedia = media
+	})
+	if inserted {
+		c.JSON(409, redfishErrorCode(baseRegistry+"ResourceInUse", fmt.Errorf("virtual media is already inserted, eject it first")))
+		return
+	}
+
+	user, _ := contextUser(c)
+	r.audit(user, sys, "InsertMedia", u.Redacted(), err)
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	if method == Upload {
+		t := r.startTask("Virtual media download from "+u.Redacted(), c.Request.URL.Path, func(ctx context.Context, p *taskProgress) error {
+			ctx = r.downloads.start(ctx, media.Token)
+			defer r.downloads.cancel(media.Token)
+
+			err := r.downloadMedia(ctx, u, sys.UnifiPort, media, p)
+			if err != nil {
+				// Media that cannot be served is ejected again.
+				r.ejectMedia(sys.UnifiPort, media.Token)
+			}
+			return err
+		})
+		acceptTask(c, t)
+		return
+	}
+
+	c.Status(204)
+}
+
+// EjectVirtualMedia implements ServerInterface.
+func (r *RedfishServer) EjectVirtualMedia(c *gin.Context, managerId string, virtualMediaId string) {
+	sys, ok := r.lookupManagerSystem(c, managerId)
+	if !ok {
+		return
+	}
+	if !checkVirtualMediaId(c, virtualMediaId) {
+		return
+	}
+
+	media, err := r.ejectMedia(sys.UnifiPort, "")
+
+	user, _ := contextUser(c)
+	if media != nil || err != nil {
+		detail := ""
+		if media != nil {
+			detail = media.ImageName
+		}
+		r.audit(user, sys, "EjectMedia", detail, err)
+	}
+	if err != nil {
+		c.JSON(500, redfishError(err))
+		return
+	}
+
+	c.Status(204)
+}
+
+// ejectMedia removes the media of the system on port, if its token matches or
+// token is empty, together with its cached copy. It returns the media ejected.
+func (r *RedfishServer) ejectMedia(port int, token string) (*virtualMediaState, error) {
+	var media *virtualMediaState
+	err := r.state.updateSystem(port, func(st *systemState) {
+		if st.VirtualMedia == nil || (token != "" && st.VirtualMedia.Token != token) {
+			return
+		}
+		media = st.VirtualMedia
+		st.VirtualMedia = nil
+	})
+
+	if media != nil {
+		r.downloads.cancel(media.Token)
+
+		if path := r.mediaCachePath(media); path != "" {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("virtual media: removing %s: %v", path, err)
+			}
+			os.Remove(path + ".part")
+		}
+	}
+
+	return media, err
+}
+
+// progressWriter reports the share of total bytes written as task progress.
+type progressWriter struct {
+	p       *taskProgress
+	written int64
+	total   int64
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.written += int64(len(b))
+	if w.total > 0 {
+		w.p.percent(int(w.written * 99 / w.total))
+	}
+	return len(b), nil
+}
+
+// downloadMedia downloads the image at u to the cache path of media inserted
+// into the system on port, through a temporary file so a partial image is
+// never served. The image is discarded if the media is ejected meanwhile.
+func (r *RedfishServer) downloadMedia(ctx context.Context, u *url.URL, port int, media *virtualMediaState, p *taskProgress) error {
+	maxSize := r.Config.VirtualMediaMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultVirtualMediaMaxSize
+	}
+	path := r.mediaCachePath(media)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("downloading %s: %s", u.Redacted(), resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return fmt.Errorf("image exceeds %d bytes", maxSize)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	w := &progressWriter{p: p, total: resp.ContentLength}
+	// The length reported by the server is not trusted.
+	n, err := io.Copy(io.MultiWriter(f, w), io.LimitReader(resp.Body, maxSize+1))
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("image exceeds %d bytes", maxSize)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("downloading %s: %w", u.Redacted(), err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// The image is moved into place under the state lock, so an eject either
+	// precedes it and the image is discarded, or follows it and removes it.
+	var moveErr error
+	err = r.state.updateSystem(port, func(st *systemState) {
+		if st.VirtualMedia == nil || st.VirtualMedia.Token != media.Token {
+			moveErr = errMediaEjected
+			return
+		}
+		moveErr = os.Rename(tmp, path)
+	})
+	return errors.Join(moveErr, err)
+}
+
+// RegisterMediaHandlers registers the routes media is served to systems on.
+// They authorize requests by the token in their path and must be registered
+// outside the authenticated routes.
+func RegisterMediaHandlers(router gin.IRouter, r *RedfishServer) {
+	router.GET(mediaPrefix+":token/:file", r.ServeMedia)
+	router.HEAD(mediaPrefix+":token/:file", r.ServeMedia)
+}
+
+// ServeMedia serves inserted media to systems, with Range support so iPXE
+// reads only the blocks it boots from. virtualMediaScript is served next to
+// the image for systems chaining to it over HTTP.
+func (r *RedfishServer) ServeMedia(c *gin.Context) {
+	port, media, ok := r.state.mediaByToken(c.Param("token"))
+	if !ok {
+		c.Status(404)
+		return
+	}
+
+	file := c.Param("file")
+	switch file {
+	case virtualMediaScript:
+		c.Data(200, "text/plain; charset=utf-8", r.sanbootScript(port, media))
+		return
+	case media.ImageName:
+	default:
+		c.Status(404)
+		return
+	}
+
+	if media.TransferMethod == string(Upload) {
+		f, err := os.Open(r.mediaCachePath(media))
+		if errors.Is(err, os.ErrNotExist) {
+			// The download task has not finished yet.
+			c.Header("Retry-After", "10")
+			c.Status(503)
+			return
+		} else if err != nil {
+			c.Status(500)
+			return
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			c.Status(500)
+			return
+		}
+		http.ServeContent(c.Writer, c.Request, media.ImageName, fi.ModTime(), f)
+		return
+	}
+
+	r.proxyMedia(c, media)
+}
+
+// proxiedMediaHeaders are the response headers passed on from the origin of
+// streamed media.
+var proxiedMediaHeaders = []string{
+	"Accept-Ranges",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+}
+
+// proxyMedia streams media from its origin, passing range requests on.
+func (r *RedfishServer) proxyMedia(c *gin.Context, media *virtualMediaState) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, media.Image, nil)
+	if err != nil {
+		c.Status(500)
+		return
+	}
+	for _, h := range []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match"} {
+		if v := c.GetHeader(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("virtual media: fetching %s: %v", media.ImageName, err)
+		c.Status(502)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range proxiedMediaHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			c.Header(h, v)
+		}
+	}
+	c.Status(resp.StatusCode)
+
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(c.Writer, resp.Body); err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("virtual media: streaming %s: %v", media.ImageName, err)
+	}
+}
+
+// NetbootScript returns the iPXE script booting the virtual media of the
+// client with the given MAC or IP address in place of virtualMediaScript,
+// while media is inserted and its boot override is Cd.
+func (r *RedfishServer) NetbootScript(mac net.HardwareAddr, addr netip.Addr, filename string) ([]byte, bool) {
+	if path.Base(filename) != virtualMediaScript {
+		return nil, false
+	}
+
+	port, ok := r.index.lookup(mac, addr)
+	if !ok {
+		return nil, false
+	}
+
+	st := r.state.system(port)
+	if st.VirtualMedia == nil || BootSource(st.BootSourceOverrideTarget) != Cd {
+		return nil, false
+	}
+
+	log.Printf("tftp: booting virtual media %s on system %d", st.VirtualMedia.ImageName, port)
+
+	return r.sanbootScript(port, st.VirtualMedia), true
+}
+
+// mediaByToken returns the port and media of the system media is inserted
+// into under token.
+func (s *stateStore) mediaByToken(token string) (int, *virtualMediaState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, st := range s.Systems {
+		if st.VirtualMedia == nil || st.VirtualMedia.Token != token {
+			continue
+		}
+		port, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		media := *st.VirtualMedia
+		return port, &media, true
+	}
+
+	return 0, nil, false
+}
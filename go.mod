@@ -1,6 +1,6 @@
 module github.com/appkins-org/go-redfish-uefi
 
-go 1.23.5
+go 1.24.0
 
 require (
 	github.com/0x5a17ed/uefi v0.7.0
@@ -16,6 +16,7 @@ require (
 	github.com/ubiquiti-community/go-unifi v1.33.7
 	go.opentelemetry.io/otel/trace v1.32.0
 	go.uber.org/multierr v1.11.0
+	golang.org/x/crypto v0.32.0
 	golang.org/x/sys v0.29.0
 )
 
@@ -67,7 +68,6 @@ require (
 	github.com/vmware-labs/yaml-jsonpath v0.3.2 // indirect
 	go.opentelemetry.io/otel v1.32.0 // indirect
 	golang.org/x/arch v0.4.0 // indirect
-	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/exp v0.0.0-20240909161429-701f63a606c0 // indirect
 	golang.org/x/mod v0.22.0 // indirect
 	golang.org/x/net v0.33.0 // indirect
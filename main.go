@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
 	"net/netip"
+	"path/filepath"
+	"time"
 
 	"github.com/appkins-org/go-redfish-uefi/api/redfish"
+	"github.com/appkins-org/go-redfish-uefi/pkg/certs"
 	"github.com/appkins-org/go-redfish-uefi/pkg/config"
 	itftp "github.com/appkins-org/go-redfish-uefi/pkg/tftp"
 	"github.com/gin-gonic/gin"
@@ -17,6 +21,9 @@ import (
 //go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen -package redfish -o api/redfish/server.gen.go -generate gin-server,models https://opendev.org/airship/go-redfish/raw/branch/master/spec/openapi.yaml
 //go:generate go run github.com/rwtodd/Go.Sed/cmd/sed-go -i "s/systemId/ComputerSystemId/g" api/redfish/server.gen.go
 
+// version is set at build time by goreleaser.
+var version = "dev"
+
 func main() {
 	conf, err := config.NewConfig()
 	if err != nil {
@@ -24,6 +31,30 @@ func main() {
 		panic(err)
 	}
 
+	var certManager *certs.Manager
+	if !conf.Tls.Disabled {
+		certFile, keyFile := conf.Tls.CertFile, conf.Tls.KeyFile
+		generate := certFile == "" && keyFile == ""
+		if generate {
+			dir := conf.Tls.Directory
+			if dir == "" {
+				dir = "/var/lib/go-redfish-uefi"
+			}
+			certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+		}
+
+		certManager, err = certs.New(certFile, keyFile, generate)
+		if err != nil {
+			log.Default().Fatal(err)
+		}
+
+		go func() {
+			if err := certManager.Watch(context.Background()); err != nil {
+				log.Printf("certs: %v", err)
+			}
+		}()
+	}
+
 	server := redfish.NewRedfishServer(redfish.RedfishServerConfig{
 		Insecure:      true,
 		UnifiUser:     conf.Unifi.Username,
@@ -31,13 +62,81 @@ func main() {
 		UnifiEndpoint: conf.Unifi.Endpoint,
 		UnifiSite:     conf.Unifi.Site,
 		UnifiDevice:   conf.Unifi.Device,
+
+		FirmwareVersion: version,
+
+		TftpRootDirectory: conf.Tftp.RootDirectory,
+
+		Port:         conf.Port,
+		Certificates: certManager,
+
+		AuthUsername:   conf.Auth.Username,
+		AuthPassword:   conf.Auth.Password,
+		AccountsFile:   conf.Auth.AccountsFile,
+		SessionTimeout: time.Duration(conf.Auth.SessionTimeout) * time.Second,
+
+		PoeBudget:       conf.Poe.Budget,
+		PoeExpectedDraw: conf.Poe.ExpectedDraw,
+
+		NetworkModes:          conf.NetworkModes,
+		QuarantineNetworkMode: conf.QuarantineNetworkMode,
+		AuditLog:              conf.AuditLog,
+		StateFile:             conf.StateFile,
+		TaskRetention:         time.Duration(conf.TaskRetention) * time.Second,
+
+		EventLogDirectory: conf.EventLog.Directory,
+		EventLogMaxSize:   int64(conf.EventLog.MaxSize) * 1024,
+		EventLogMaxFiles:  conf.EventLog.MaxFiles,
+
+		VirtualMediaBaseURL:        mediaBaseURL(conf, certManager != nil),
+		VirtualMediaCacheDirectory: conf.VirtualMedia.CacheDirectory,
+		VirtualMediaMaxSize:        int64(conf.VirtualMedia.MaxSize) << 20,
+
+		SecureBootDefaultKeys: conf.SecureBoot.DefaultKeys,
+
+		FirmwareRepository:    conf.Update.Repository,
+		FirmwareUploadMaxSize: int64(conf.Update.MaxUploadSize) << 20,
+
+		EventSubscriptionsFile: conf.Events.SubscriptionsFile,
+		EventRetryAttempts:     conf.Events.RetryAttempts,
+		EventRetryInterval:     time.Duration(conf.Events.RetryInterval) * time.Second,
+		ControllerPollInterval: time.Duration(conf.Events.PollInterval) * time.Second,
+
+		Models:                   conf.Models,
+		FingerprintModels:        conf.FingerprintModels,
+		ExcludedDeviceCategories: conf.ExcludedDeviceCategories,
+		ExcludedOUIs:             conf.ExcludedOUIs,
+
+		DhcpNetwork:      conf.Dhcp.Network,
+		DhcpBootServer:   conf.Dhcp.BootServer,
+		DhcpBootFilename: conf.Dhcp.BootFilename,
+		DhcpReconcile:    conf.Dhcp.Reconcile,
+
+		Systems: conf.Systems,
 	})
 
+	go server.Monitor(context.Background())
+
 	addr := fmt.Sprintf("%s:%d", conf.Address, conf.Port)
 
 	h := gin.Default()
 
-	redfish.RegisterHandlers(h, server)
+	api := h.Group("/", server.Authenticate)
+
+	redfish.RegisterHandlers(api, server)
+	redfish.RegisterExtendedHandlers(api, server)
+	redfish.RegisterMediaHandlers(h, server)
+	redfish.RegisterErrorHandlers(h)
+
+	if conf.VirtualMedia.Port != 0 {
+		// iPXE does not trust the API certificate, so media is also served
+		// over plain HTTP.
+		m := gin.Default()
+		redfish.RegisterMediaHandlers(m, server)
+		go func() {
+			log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%d", conf.Address, conf.VirtualMedia.Port), m))
+		}()
+	}
 
 	s := &http.Server{
 		Handler: h,
@@ -46,14 +145,49 @@ func main() {
 
 	go func() {
 		// And we serve HTTP until the world ends.
-		log.Fatal(s.ListenAndServe())
+		if certManager == nil {
+			log.Fatal(s.ListenAndServe())
+		}
+
+		s.TLSConfig = &tls.Config{
+			GetCertificate: certManager.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+		log.Fatal(s.ListenAndServeTLS("", ""))
 	}()
 
 	tftpHandler := &itftp.Handler{
 		RootDirectory: conf.Tftp.RootDirectory,
+		Netboot:       server.Netboot,
+		Generate:      server.NetbootScript,
+		Served:        server.NetbootServed,
+		Received:      server.NetbootReceived,
 	}
 
 	ts := tftp.NewServer(tftpHandler.HandleRead, tftpHandler.HandleWrite)
 
 	log.Fatal(itftp.ListenAndServe(context.Background(), netip.AddrPortFrom(netip.MustParseAddr(conf.Address), 69), ts))
 }
+
+// mediaBaseURL returns the URL systems fetch virtual media from, defaulting
+// to the plain HTTP media port, or the API, on the address systems boot from.
+func mediaBaseURL(conf *config.Config, tls bool) string {
+	if conf.VirtualMedia.BaseURL != "" {
+		return conf.VirtualMedia.BaseURL
+	}
+
+	host := conf.Dhcp.BootServer
+	if host == "" {
+		host = conf.Address
+	}
+
+	if conf.VirtualMedia.Port != 0 {
+		return fmt.Sprintf("http://%s:%d", host, conf.VirtualMedia.Port)
+	}
+
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, conf.Port)
+}
@@ -0,0 +1,247 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Manager serves the TLS certificate stored in a cert and key file pair and
+// reloads it when either file changes.
+type Manager struct {
+	CertFile string
+	KeyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// New loads the certificate from certFile and keyFile. When generate is set
+// and the files do not exist, a self-signed certificate is generated and
+// written to them first.
+func New(certFile, keyFile string, generate bool) (*Manager, error) {
+	m := &Manager{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+
+	if generate {
+		_, certErr := os.Stat(certFile)
+		_, keyErr := os.Stat(keyFile)
+		if errors.Is(certErr, os.ErrNotExist) || errors.Is(keyErr, os.ErrNotExist) {
+			certPEM, keyPEM, err := selfSigned()
+			if err != nil {
+				return nil, fmt.Errorf("generating self-signed certificate: %w", err)
+			}
+			if err := m.write(certPEM, keyPEM); err != nil {
+				return nil, err
+			}
+			log.Printf("certs: generated self-signed certificate %s", certFile)
+		}
+	}
+
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// selfSigned returns a PEM encoded self-signed certificate and its key, valid
+// for the host name and every address of this machine.
+func selfSigned() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   hostname,
+			Organization: []string{"go-redfish-uefi"},
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{hostname, "localhost"},
+	}
+
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				template.IPAddresses = append(template.IPAddresses, ipNet.IP)
+			}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
+
+// writeFile writes b to a temporary file and renames it over path so readers
+// never observe a partial file.
+func writeFile(path string, b []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (m *Manager) write(certPEM, keyPEM []byte) error {
+	if err := writeFile(m.KeyFile, keyPEM, 0600); err != nil {
+		return err
+	}
+	return writeFile(m.CertFile, certPEM, 0644)
+}
+
+// Reload reads the certificate from its files. The previous certificate is
+// kept when they are invalid.
+func (m *Manager) Reload() error {
+	cert, err := tls.LoadX509KeyPair(m.CertFile, m.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+
+	m.mu.Lock()
+	m.cert = &cert
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Replace validates a PEM encoded certificate chain and private key, writes
+// them to the certificate files and serves them from now on.
+func (m *Manager) Replace(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	if err := m.write(certPEM, keyPEM); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.cert = &cert
+	m.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate returns the current certificate. It is meant for
+// tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cert, nil
+}
+
+// Certificate returns the parsed leaf of the current certificate.
+func (m *Manager) Certificate() (*x509.Certificate, error) {
+	m.mu.RLock()
+	cert := m.cert
+	m.mu.RUnlock()
+
+	if cert.Leaf != nil {
+		return cert.Leaf, nil
+	}
+	return x509.ParseCertificate(cert.Certificate[0])
+}
+
+// Watch reloads the certificate whenever its files change until ctx is done.
+// The directories are watched rather than the files so certificates replaced
+// by renaming, as done by most tooling, are picked up.
+func (m *Manager) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, dir := range []string{filepath.Dir(m.CertFile), filepath.Dir(m.KeyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	certFile, keyFile := filepath.Clean(m.CertFile), filepath.Clean(m.KeyFile)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			name := filepath.Clean(event.Name)
+			if name != certFile && name != keyFile {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			if err := m.Reload(); err != nil {
+				// The pair is often replaced one file at a time, so a
+				// mismatch is expected until both are written.
+				log.Printf("certs: %v", err)
+				continue
+			}
+			log.Printf("certs: reloaded %s", m.CertFile)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("certs: watching: %v", err)
+		}
+	}
+}
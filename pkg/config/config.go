@@ -23,12 +23,115 @@ type TftpConfig struct {
 	RootDirectory string `yaml:"root_directory" mapstructure:"root_directory"`
 }
 
+type DhcpConfig struct {
+	Network      string `yaml:"network" mapstructure:"network"`
+	BootServer   string `yaml:"boot_server" mapstructure:"boot_server"`
+	BootFilename string `yaml:"boot_filename" mapstructure:"boot_filename"`
+	Reconcile    bool   `yaml:"reconcile" mapstructure:"reconcile"`
+}
+
+type PoeConfig struct {
+	Budget       float64 `yaml:"budget" mapstructure:"budget"`
+	ExpectedDraw float64 `yaml:"expected_draw" mapstructure:"expected_draw"`
+}
+
+type AuthConfig struct {
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password"`
+	// AccountsFile is where the accounts managed through the AccountService
+	// are stored.
+	AccountsFile string `yaml:"accounts_file" mapstructure:"accounts_file"`
+	// SessionTimeout is the idle timeout of sessions in seconds.
+	SessionTimeout int `yaml:"session_timeout" mapstructure:"session_timeout"`
+}
+
+type TlsConfig struct {
+	// Disabled serves the API over plain HTTP.
+	Disabled bool `yaml:"disabled" mapstructure:"disabled"`
+	// CertFile and KeyFile hold the certificate served over HTTPS. A
+	// self-signed certificate is generated in Directory when they are empty.
+	CertFile  string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile   string `yaml:"key_file" mapstructure:"key_file"`
+	Directory string `yaml:"directory" mapstructure:"directory"`
+}
+
+type EventsConfig struct {
+	// SubscriptionsFile is where event subscriptions are stored.
+	SubscriptionsFile string `yaml:"subscriptions_file" mapstructure:"subscriptions_file"`
+	// RetryAttempts and RetryInterval, in seconds, are the initial delivery
+	// retry policy. The interval doubles with every retry.
+	RetryAttempts int `yaml:"retry_attempts" mapstructure:"retry_attempts"`
+	RetryInterval int `yaml:"retry_interval" mapstructure:"retry_interval"`
+	// PollInterval is how often the controller is polled for power
+	// transitions and outages, in seconds.
+	PollInterval int `yaml:"poll_interval" mapstructure:"poll_interval"`
+}
+
+type UpdateConfig struct {
+	// Repository is the directory firmware uploads are staged in. Uploads
+	// are disabled when empty.
+	Repository string `yaml:"repository" mapstructure:"repository"`
+	// MaxUploadSize is the size in MiB uploads are limited to.
+	MaxUploadSize int `yaml:"max_upload_size" mapstructure:"max_upload_size"`
+}
+
+type SecureBootConfig struct {
+	// DefaultKeys is the directory holding the EFI signature lists
+	// ResetAllKeysToDefault restores, one per database: PK.esl, KEK.esl,
+	// db.esl and dbx.esl.
+	DefaultKeys string `yaml:"default_keys" mapstructure:"default_keys"`
+}
+
+type VirtualMediaConfig struct {
+	// BaseURL is the URL systems fetch virtual media from. It defaults to
+	// the media port, or the API, on dhcp.boot_server or address.
+	BaseURL string `yaml:"base_url" mapstructure:"base_url"`
+	// Port serves virtual media over plain HTTP in addition to the API.
+	Port int `yaml:"port" mapstructure:"port"`
+	// CacheDirectory is where media inserted with the Upload transfer method
+	// is downloaded to. Only Stream is supported when empty.
+	CacheDirectory string `yaml:"cache_directory" mapstructure:"cache_directory"`
+	// MaxSize is the size in MiB downloaded media is limited to.
+	MaxSize int `yaml:"max_size" mapstructure:"max_size"`
+}
+
+type EventLogConfig struct {
+	// Directory is where the event log of every system is kept. The event
+	// logs are disabled when empty.
+	Directory string `yaml:"directory" mapstructure:"directory"`
+	// MaxSize is the size in KiB an event log is rotated at, and MaxFiles how
+	// many rotated files are kept.
+	MaxSize  int `yaml:"max_size" mapstructure:"max_size"`
+	MaxFiles int `yaml:"max_files" mapstructure:"max_files"`
+}
+
 type Config struct {
-	Address string                           `yaml:"address" mapstructure:"address"`
-	Port    int                              `yaml:"port" mapstructure:"port"`
-	Unifi   UnifiConfig                      `yaml:"unifi" mapstructure:"unifi"`
-	Tftp    TftpConfig                       `yaml:"tftp" mapstructure:"tftp"`
-	Systems map[string]redfish.RedfishSystem `yaml:"systems" mapstructure:"systems"`
+	Address      string                           `yaml:"address" mapstructure:"address"`
+	Port         int                              `yaml:"port" mapstructure:"port"`
+	Unifi        UnifiConfig                      `yaml:"unifi" mapstructure:"unifi"`
+	Auth         AuthConfig                       `yaml:"auth" mapstructure:"auth"`
+	Tls          TlsConfig                        `yaml:"tls" mapstructure:"tls"`
+	Tftp         TftpConfig                       `yaml:"tftp" mapstructure:"tftp"`
+	Poe          PoeConfig                        `yaml:"poe" mapstructure:"poe"`
+	Dhcp         DhcpConfig                       `yaml:"dhcp" mapstructure:"dhcp"`
+	Events       EventsConfig                     `yaml:"events" mapstructure:"events"`
+	EventLog     EventLogConfig                   `yaml:"event_log" mapstructure:"event_log"`
+	Update       UpdateConfig                     `yaml:"update" mapstructure:"update"`
+	VirtualMedia VirtualMediaConfig               `yaml:"virtual_media" mapstructure:"virtual_media"`
+	SecureBoot   SecureBootConfig                 `yaml:"secure_boot" mapstructure:"secure_boot"`
+	Systems      map[string]redfish.RedfishSystem `yaml:"systems" mapstructure:"systems"`
+
+	NetworkModes          map[string]string `yaml:"network_modes" mapstructure:"network_modes"`
+	QuarantineNetworkMode string            `yaml:"quarantine_network_mode" mapstructure:"quarantine_network_mode"`
+	AuditLog              string            `yaml:"audit_log" mapstructure:"audit_log"`
+	StateFile             string            `yaml:"state_file" mapstructure:"state_file"`
+	// TaskRetention is how long finished tasks are kept in seconds.
+	TaskRetention int `yaml:"task_retention" mapstructure:"task_retention"`
+
+	Models                   map[string]redfish.SystemModel `yaml:"models" mapstructure:"models"`
+	FingerprintModels        map[string]string              `yaml:"fingerprint_models" mapstructure:"fingerprint_models"`
+	ExcludedDeviceCategories []int                          `yaml:"excluded_device_categories" mapstructure:"excluded_device_categories"`
+	ExcludedOUIs             []string                       `yaml:"excluded_ouis" mapstructure:"excluded_ouis"`
 }
 
 func NewConfig() (conf *Config, err error) {
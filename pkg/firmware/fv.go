@@ -0,0 +1,156 @@
+package firmware
+
+import (
+	"encoding/binary"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+)
+
+var (
+	// ffs2Guid and ffs3Guid identify firmware volumes holding FFS files.
+	ffs2Guid = efiguid.MustFromString("8c8ce578-8a3d-4f1c-9935-896185c32dd3")
+	ffs3Guid = efiguid.MustFromString("5473c07a-3dcb-4dca-bd6f-1e9689e7349a")
+)
+
+const (
+	fvHeaderSize = 0x38
+
+	ffsFileHeaderSize  = 24
+	ffsFileHeader2Size = 32
+	ffsAttribLargeFile = 0x01
+	ffsTypePad         = 0xf0
+
+	sectionGuidDefined = 0x02
+	sectionPE32        = 0x10
+	sectionTE          = 0x12
+	sectionVersion     = 0x14
+	sectionFvImage     = 0x17
+	sectionRaw         = 0x19
+
+	// guidedProcessingRequired marks GUID defined sections whose data must
+	// be decoded, typically decompressed, before it can be read.
+	guidedProcessingRequired = 0x01
+
+	// maxSectionDepth bounds the nesting of volumes and sections followed.
+	maxSectionDepth = 8
+)
+
+func align8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// uint24 decodes the 24-bit sizes of FFS file and section headers.
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// firmwareVolumes returns the FFS firmware volumes found in b, each as the
+// bytes it spans.
+func firmwareVolumes(b []byte) [][]byte {
+	var fvs [][]byte
+	for i := 0; i+fvHeaderSize <= len(b); i += 8 {
+		if string(b[i+40:i+44]) != fvSignature {
+			continue
+		}
+
+		length := binary.LittleEndian.Uint64(b[i+32:])
+		headerLength := int(binary.LittleEndian.Uint16(b[i+48:]))
+		if length < fvHeaderSize || length > uint64(len(b)-i) || headerLength < fvHeaderSize || uint64(headerLength) > length {
+			continue
+		}
+
+		var fs efiguid.GUID
+		copy(fs[:], b[i+16:i+32])
+		if fs == ffs2Guid || fs == ffs3Guid {
+			fvs = append(fvs, b[i:i+int(length)])
+		}
+		i = align8(i+int(length)) - 8
+	}
+	return fvs
+}
+
+// walkVolume calls visit with the type and data of every leaf section of the
+// files of a firmware volume, until visit returns false. It reports whether
+// the walk completed.
+func walkVolume(fv []byte, depth int, visit func(typ byte, data []byte) bool) bool {
+	if depth > maxSectionDepth {
+		return true
+	}
+
+	off := int(binary.LittleEndian.Uint16(fv[48:]))
+	if ext := int(binary.LittleEndian.Uint16(fv[52:])); ext != 0 && ext+20 <= len(fv) {
+		off = ext + int(binary.LittleEndian.Uint32(fv[ext+16:]))
+	}
+
+	for off = align8(off); off+ffsFileHeaderSize <= len(fv); {
+		h := fv[off:]
+
+		size, headerSize := uint24(h[20:]), ffsFileHeaderSize
+		if h[19]&ffsAttribLargeFile != 0 {
+			if off+ffsFileHeader2Size > len(fv) {
+				break
+			}
+			size, headerSize = int(binary.LittleEndian.Uint32(h[24:])), ffsFileHeader2Size
+		}
+		// Free space is erased, so its size never fits the volume.
+		if size < headerSize || size > len(fv)-off {
+			break
+		}
+
+		if h[18] != ffsTypePad && !walkSections(h[headerSize:size], depth, visit) {
+			return false
+		}
+
+		off = align8(off + size)
+	}
+
+	return true
+}
+
+// walkSections calls visit with the leaf sections of b, following GUID
+// defined sections that need no processing and nested firmware volumes.
+func walkSections(b []byte, depth int, visit func(typ byte, data []byte) bool) bool {
+	for off := 0; off+4 <= len(b); {
+		h := b[off:]
+
+		size, typ, headerSize := uint24(h), h[3], 4
+		if size == 0xffffff {
+			if off+8 > len(b) {
+				break
+			}
+			size, headerSize = int(binary.LittleEndian.Uint32(h[4:])), 8
+		}
+		if size < headerSize || size > len(b)-off {
+			break
+		}
+		data := h[headerSize:size]
+
+		switch typ {
+		case sectionGuidDefined:
+			if len(data) < 20 {
+				break
+			}
+			dataOffset := int(binary.LittleEndian.Uint16(data[16:]))
+			attrs := binary.LittleEndian.Uint16(data[18:])
+			if attrs&guidedProcessingRequired == 0 && dataOffset >= headerSize+20 && dataOffset <= size {
+				if !walkSections(h[dataOffset:size], depth+1, visit) {
+					return false
+				}
+			}
+		case sectionFvImage:
+			for _, fv := range firmwareVolumes(data) {
+				if !walkVolume(fv, depth+1, visit) {
+					return false
+				}
+			}
+		default:
+			if !visit(typ, data) {
+				return false
+			}
+		}
+
+		off = align4(off + size)
+	}
+
+	return true
+}
@@ -0,0 +1,129 @@
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+	"github.com/0x5a17ed/uefi/efi/efivario"
+)
+
+var (
+	// GlobalVariableGuid is the vendor GUID of PK and KEK.
+	GlobalVariableGuid = efiguid.MustFromString("8be4df61-93ca-11d2-aa0d-00e098032b8c")
+	// ImageSecurityDatabaseGuid is the vendor GUID of db and dbx.
+	ImageSecurityDatabaseGuid = efiguid.MustFromString("d719b2cb-3d3a-4596-a3bc-dad00e67656f")
+
+	// CertX509Guid is the signature type of DER encoded X.509 certificates.
+	CertX509Guid = efiguid.MustFromString("a5c059a1-94e4-4aa7-87b5-ab155c2bf072")
+	// CertSha256Guid is the signature type of SHA-256 image hashes.
+	CertSha256Guid = efiguid.MustFromString("c1c41626-504c-4092-aca9-41f936934328")
+)
+
+// SecureBootAttributes are the attributes of the Secure Boot key databases.
+const SecureBootAttributes = efivario.NonVolatile | efivario.BootServiceAccess | efivario.RuntimeAccess | efivario.TimeBasedAuthenticatedWriteAccess
+
+const signatureListHeaderSize = 28
+
+// Signature is an entry of an EFI_SIGNATURE_LIST.
+type Signature struct {
+	Owner efiguid.GUID
+	Data  []byte
+}
+
+// SignatureList is an EFI_SIGNATURE_LIST holding signatures of one type.
+type SignatureList struct {
+	Type       efiguid.GUID
+	Header     []byte
+	Signatures []Signature
+}
+
+// ParseSignatureLists parses the EFI_SIGNATURE_LISTs stored in a Secure Boot
+// key database variable.
+func ParseSignatureLists(b []byte) ([]SignatureList, error) {
+	var lists []SignatureList
+
+	for len(b) > 0 {
+		if len(b) < signatureListHeaderSize {
+			return nil, fmt.Errorf("truncated signature list header")
+		}
+
+		l := SignatureList{}
+		copy(l.Type[:], b[:16])
+		listSize := int(binary.LittleEndian.Uint32(b[16:]))
+		headerSize := int(binary.LittleEndian.Uint32(b[20:]))
+		sigSize := int(binary.LittleEndian.Uint32(b[24:]))
+
+		if listSize < signatureListHeaderSize+headerSize || listSize > len(b) || sigSize < 16 {
+			return nil, fmt.Errorf("invalid signature list of size %d", listSize)
+		}
+
+		l.Header = bytes.Clone(b[signatureListHeaderSize : signatureListHeaderSize+headerSize])
+
+		sigs := b[signatureListHeaderSize+headerSize : listSize]
+		if len(sigs)%sigSize != 0 {
+			return nil, fmt.Errorf("signature list size %d is not a multiple of %d", len(sigs), sigSize)
+		}
+
+		for ; len(sigs) > 0; sigs = sigs[sigSize:] {
+			s := Signature{Data: bytes.Clone(sigs[16:sigSize])}
+			copy(s.Owner[:], sigs[:16])
+			l.Signatures = append(l.Signatures, s)
+		}
+
+		lists = append(lists, l)
+		b = b[listSize:]
+	}
+
+	return lists, nil
+}
+
+// EncodeSignatureLists encodes signature lists for a Secure Boot key
+// database variable. Signatures of a list must all have the same size;
+// X.509 certificates are therefore stored one per list.
+func EncodeSignatureLists(lists []SignatureList) []byte {
+	var b []byte
+
+	for _, l := range lists {
+		if len(l.Signatures) == 0 {
+			continue
+		}
+
+		sigSize := 16 + len(l.Signatures[0].Data)
+
+		h := make([]byte, signatureListHeaderSize)
+		copy(h, l.Type[:])
+		binary.LittleEndian.PutUint32(h[16:], uint32(signatureListHeaderSize+len(l.Header)+sigSize*len(l.Signatures)))
+		binary.LittleEndian.PutUint32(h[20:], uint32(len(l.Header)))
+		binary.LittleEndian.PutUint32(h[24:], uint32(sigSize))
+
+		b = append(b, h...)
+		b = append(b, l.Header...)
+		for _, s := range l.Signatures {
+			b = append(b, s.Owner[:]...)
+			b = append(b, s.Data...)
+		}
+	}
+
+	return b
+}
+
+// Timestamp encodes t as the EFI_TIME of an authenticated variable.
+func Timestamp(t time.Time) (ts [16]byte) {
+	t = t.UTC()
+	binary.LittleEndian.PutUint16(ts[0:], uint16(t.Year()))
+	ts[2] = byte(t.Month())
+	ts[3] = byte(t.Day())
+	ts[4] = byte(t.Hour())
+	ts[5] = byte(t.Minute())
+	ts[6] = byte(t.Second())
+	return ts
+}
+
+// Authenticated reports whether the variable store of the image holds
+// authenticated variables, which Secure Boot requires.
+func (img *Image) Authenticated() bool {
+	return img.authenticated
+}
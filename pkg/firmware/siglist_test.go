@@ -0,0 +1,138 @@
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestSignatureListsRoundTrip(t *testing.T) {
+	owner := testGuid
+	hash := func(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+
+	tests := []struct {
+		name  string
+		lists []SignatureList
+	}{
+		{
+			name:  "empty",
+			lists: nil,
+		},
+		{
+			name: "certificate",
+			lists: []SignatureList{
+				{Type: CertX509Guid, Signatures: []Signature{{Owner: owner, Data: []byte("DER certificate")}}},
+			},
+		},
+		{
+			name: "hashes",
+			lists: []SignatureList{
+				{Type: CertSha256Guid, Signatures: []Signature{{Owner: owner, Data: hash(1)}, {Owner: owner, Data: hash(2)}}},
+			},
+		},
+		{
+			name: "header",
+			lists: []SignatureList{
+				{Type: CertSha256Guid, Header: []byte{1, 2, 3, 4}, Signatures: []Signature{{Owner: owner, Data: hash(3)}}},
+			},
+		},
+		{
+			name: "several lists",
+			lists: []SignatureList{
+				{Type: CertX509Guid, Signatures: []Signature{{Owner: owner, Data: []byte("first")}}},
+				{Type: CertX509Guid, Signatures: []Signature{{Owner: GlobalVariableGuid, Data: []byte("second certificate")}}},
+				{Type: CertSha256Guid, Signatures: []Signature{{Owner: owner, Data: hash(4)}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSignatureLists(EncodeSignatureLists(tt.lists))
+			if err != nil {
+				t.Fatalf("ParseSignatureLists: %v", err)
+			}
+
+			// Parsing returns an empty rather than a nil header.
+			want := make([]SignatureList, 0, len(tt.lists))
+			for _, l := range tt.lists {
+				if l.Header == nil {
+					l.Header = []byte{}
+				}
+				want = append(want, l)
+			}
+			if len(want) == 0 {
+				want = nil
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("lists = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeSignatureListsSkipsEmpty(t *testing.T) {
+	b := EncodeSignatureLists([]SignatureList{
+		{Type: CertX509Guid},
+		{Type: CertX509Guid, Signatures: []Signature{{Owner: testGuid, Data: []byte("cert")}}},
+	})
+
+	lists, err := ParseSignatureLists(b)
+	if err != nil {
+		t.Fatalf("ParseSignatureLists: %v", err)
+	}
+	if len(lists) != 1 || len(lists[0].Signatures) != 1 {
+		t.Fatalf("lists = %+v, want one list with one signature", lists)
+	}
+}
+
+func TestParseSignatureListsErrors(t *testing.T) {
+	valid := EncodeSignatureLists([]SignatureList{
+		{Type: CertSha256Guid, Signatures: []Signature{{Owner: testGuid, Data: make([]byte, 32)}}},
+	})
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{
+			name:   "truncated header",
+			modify: func(b []byte) []byte { return b[:signatureListHeaderSize-1] },
+		},
+		{
+			name:   "truncated list",
+			modify: func(b []byte) []byte { return b[:len(b)-1] },
+		},
+		{
+			name: "signature size too small",
+			modify: func(b []byte) []byte {
+				binary.LittleEndian.PutUint32(b[24:], 15)
+				return b
+			},
+		},
+		{
+			name: "header exceeds list",
+			modify: func(b []byte) []byte {
+				binary.LittleEndian.PutUint32(b[20:], uint32(len(b)))
+				return b
+			},
+		},
+		{
+			name: "size not a multiple of the signature size",
+			modify: func(b []byte) []byte {
+				binary.LittleEndian.PutUint32(b[24:], 20)
+				return b
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSignatureLists(tt.modify(bytes.Clone(valid))); err == nil {
+				t.Fatal("ParseSignatureLists succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+	"github.com/0x5a17ed/uefi/efi/efivario"
+)
+
+var (
+	// nvDataFvGuid identifies the firmware volume holding the variable store.
+	nvDataFvGuid = efiguid.MustFromString("fff12b8d-7696-4c8b-a985-2747075b4f50")
+
+	authenticatedVariableGuid = efiguid.MustFromString("aaf32c78-947b-439a-a180-2e144ec37792")
+	variableGuid              = efiguid.MustFromString("ddcf3616-3275-4164-98b6-fe85707ffe7d")
+
+	// ErrNoVariableStore is returned for images without an NV variable store.
+	ErrNoVariableStore = errors.New("no variable store found")
+)
+
+const (
+	fvSignature = "_FVH"
+
+	variableStoreHeaderSize = 28
+	variableStoreFormatted  = 0x5a
+	variableStoreHealthy    = 0xfe
+
+	variableStartId = 0x55aa
+
+	authenticatedHeaderSize = 60
+	headerSize              = 32
+
+	varAdded               = 0x3f
+	varInDeletedTransition = 0xfe
+	varAddedInDeletion     = varAdded & varInDeletedTransition
+)
+
+// Variable is an EFI variable of a firmware image.
+type Variable struct {
+	Name       string
+	Guid       efiguid.GUID
+	Attributes efivario.Attributes
+	Data       []byte
+
+	// MonotonicCount, TimeStamp and PubKeyIndex are only stored in
+	// authenticated variable stores.
+	MonotonicCount uint64
+	TimeStamp      [16]byte
+	PubKeyIndex    uint32
+}
+
+// Image is a firmware image with an NV variable store, such as RPI_EFI.fd.
+type Image struct {
+	raw []byte
+
+	// storeOffset and storeSize delimit the variables following the variable
+	// store header.
+	storeOffset   int
+	storeSize     int
+	authenticated bool
+
+	Variables []*Variable
+}
+
+// ReadFile reads a firmware image from path.
+func ReadFile(path string) (*Image, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// findVariableStore returns the offset of the variable store header of the
+// NV data firmware volume in b.
+func findVariableStore(b []byte) (int, error) {
+	for i := 0; i+0x38 <= len(b); i += 8 {
+		if string(b[i+40:i+44]) != fvSignature {
+			continue
+		}
+		if !bytes.Equal(b[i+16:i+32], nvDataFvGuid[:]) {
+			continue
+		}
+		headerLength := int(binary.LittleEndian.Uint16(b[i+48:]))
+		return i + headerLength, nil
+	}
+	return 0, ErrNoVariableStore
+}
+
+// Parse parses a firmware image. The image is kept so the variable store can
+// be rewritten in place by Bytes.
+func Parse(b []byte) (*Image, error) {
+	off, err := findVariableStore(b)
+	if err != nil {
+		return nil, err
+	}
+	if off+variableStoreHeaderSize > len(b) {
+		return nil, fmt.Errorf("truncated variable store header at %#x", off)
+	}
+
+	var guid efiguid.GUID
+	copy(guid[:], b[off:off+16])
+
+	img := &Image{
+		raw: bytes.Clone(b),
+	}
+
+	switch guid {
+	case authenticatedVariableGuid:
+		img.authenticated = true
+	case variableGuid:
+	default:
+		return nil, fmt.Errorf("unknown variable store format %s", guid)
+	}
+
+	size := int(binary.LittleEndian.Uint32(b[off+16:]))
+	if b[off+20] != variableStoreFormatted || b[off+21] != variableStoreHealthy {
+		return nil, fmt.Errorf("variable store at %#x is not formatted", off)
+	}
+	if size < variableStoreHeaderSize || off+size > len(b) {
+		return nil, fmt.Errorf("invalid variable store size %#x", size)
+	}
+
+	img.storeOffset = off + variableStoreHeaderSize
+	img.storeSize = size - variableStoreHeaderSize
+
+	if err := img.parseVariables(); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+func (img *Image) parseVariables() error {
+	store := img.raw[img.storeOffset : img.storeOffset+img.storeSize]
+
+	hdrSize := headerSize
+	if img.authenticated {
+		hdrSize = authenticatedHeaderSize
+	}
+
+	for off := 0; off+hdrSize <= len(store); {
+		h := store[off:]
+		if binary.LittleEndian.Uint16(h) != variableStartId {
+			break
+		}
+
+		state := h[2]
+		v := &Variable{
+			Attributes: efivario.Attributes(binary.LittleEndian.Uint32(h[4:])),
+		}
+
+		var nameSize, dataSize int
+		if img.authenticated {
+			v.MonotonicCount = binary.LittleEndian.Uint64(h[8:])
+			copy(v.TimeStamp[:], h[16:32])
+			v.PubKeyIndex = binary.LittleEndian.Uint32(h[32:])
+			nameSize = int(binary.LittleEndian.Uint32(h[36:]))
+			dataSize = int(binary.LittleEndian.Uint32(h[40:]))
+			copy(v.Guid[:], h[44:60])
+		} else {
+			nameSize = int(binary.LittleEndian.Uint32(h[8:]))
+			dataSize = int(binary.LittleEndian.Uint32(h[12:]))
+			copy(v.Guid[:], h[16:32])
+		}
+
+		end := off + hdrSize + nameSize + dataSize
+		if nameSize < 0 || dataSize < 0 || end > len(store) {
+			return fmt.Errorf("truncated variable at %#x", img.storeOffset+off)
+		}
+
+		if state == varAdded || state == varAddedInDeletion {
+			v.Name = decodeName(h[hdrSize : hdrSize+nameSize])
+			v.Data = bytes.Clone(h[hdrSize+nameSize : hdrSize+nameSize+dataSize])
+			img.Variables = append(img.Variables, v)
+		}
+
+		off = align4(end)
+	}
+
+	return nil
+}
+
+func decodeName(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+func encodeName(name string) []byte {
+	u := append(utf16.Encode([]rune(name)), 0)
+	b := make([]byte, len(u)*2)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+// Get returns the variable with the given name and vendor GUID.
+func (img *Image) Get(name string, guid efiguid.GUID) (*Variable, bool) {
+	for _, v := range img.Variables {
+		if v.Name == name && v.Guid == guid {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Set adds a variable or replaces the one with the same name and GUID.
+func (img *Image) Set(v Variable) {
+	if cur, ok := img.Get(v.Name, v.Guid); ok {
+		*cur = v
+		return
+	}
+	img.Variables = append(img.Variables, &v)
+}
+
+// Delete removes a variable and reports whether it existed.
+func (img *Image) Delete(name string, guid efiguid.GUID) bool {
+	for i, v := range img.Variables {
+		if v.Name == name && v.Guid == guid {
+			img.Variables = append(img.Variables[:i], img.Variables[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// CopyVariables sets every variable of from in img, so the settings stored in
+// an image survive replacing it with another firmware release.
+func (img *Image) CopyVariables(from *Image) {
+	for _, v := range from.Variables {
+		img.Set(*v)
+	}
+}
+
+// Bytes returns the image with its variable store rewritten to hold exactly
+// the current variables. Deleted and superseded entries are reclaimed the
+// same way the firmware does when its store runs full.
+func (img *Image) Bytes() ([]byte, error) {
+	store := bytes.Repeat([]byte{0xff}, img.storeSize)
+
+	off := 0
+	for _, v := range img.Variables {
+		name := encodeName(v.Name)
+
+		hdrSize := headerSize
+		if img.authenticated {
+			hdrSize = authenticatedHeaderSize
+		}
+
+		end := off + hdrSize + len(name) + len(v.Data)
+		if end > len(store) {
+			return nil, fmt.Errorf("variable store full writing %s", v.Name)
+		}
+
+		h := store[off:]
+		binary.LittleEndian.PutUint16(h, variableStartId)
+		h[2] = varAdded
+		h[3] = 0
+		binary.LittleEndian.PutUint32(h[4:], uint32(v.Attributes))
+
+		if img.authenticated {
+			binary.LittleEndian.PutUint64(h[8:], v.MonotonicCount)
+			copy(h[16:32], v.TimeStamp[:])
+			binary.LittleEndian.PutUint32(h[32:], v.PubKeyIndex)
+			binary.LittleEndian.PutUint32(h[36:], uint32(len(name)))
+			binary.LittleEndian.PutUint32(h[40:], uint32(len(v.Data)))
+			copy(h[44:60], v.Guid[:])
+		} else {
+			binary.LittleEndian.PutUint32(h[8:], uint32(len(name)))
+			binary.LittleEndian.PutUint32(h[12:], uint32(len(v.Data)))
+			copy(h[16:32], v.Guid[:])
+		}
+
+		copy(h[hdrSize:], name)
+		copy(h[hdrSize+len(name):], v.Data)
+
+		off = align4(end)
+	}
+
+	b := bytes.Clone(img.raw)
+	copy(b[img.storeOffset:], store)
+
+	return b, nil
+}
+
+// WriteFile writes the image to a temporary file and renames it over path so
+// a TFTP transfer never reads a partial image.
+func (img *Image) WriteFile(path string) error {
+	b, err := img.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Uint32 returns the data of a variable holding a UINT32.
+func (v *Variable) Uint32() (uint32, bool) {
+	if len(v.Data) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(v.Data), true
+}
+
+// Uint32Variable returns a variable holding a UINT32.
+func Uint32Variable(name string, guid efiguid.GUID, attrs efivario.Attributes, value uint32) Variable {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, value)
+	return Variable{
+		Name:       name,
+		Guid:       guid,
+		Attributes: attrs,
+		Data:       data,
+	}
+}
@@ -0,0 +1,314 @@
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+	"github.com/0x5a17ed/uefi/efi/efivario"
+)
+
+const (
+	// fixturePadding precedes the firmware volume, as the boot code does in
+	// RPI_EFI.fd.
+	fixturePadding  = 64
+	fixtureFvHeader = 0x48
+)
+
+var testGuid = efiguid.MustFromString("3ab0a9b8-4f6c-4b53-8e1c-31c2a8b5f0d1")
+
+// newFixture returns a synthetic image holding an empty NV data firmware
+// volume whose variable store has room for storeSize bytes of variables.
+func newFixture(authenticated bool, storeSize int) []byte {
+	b := bytes.Repeat([]byte{0xee}, fixturePadding+fixtureFvHeader+variableStoreHeaderSize+storeSize+16)
+
+	fv := b[fixturePadding:]
+	copy(fv[16:32], nvDataFvGuid[:])
+	copy(fv[40:44], fvSignature)
+	binary.LittleEndian.PutUint16(fv[48:], fixtureFvHeader)
+
+	store := fv[fixtureFvHeader:]
+	guid := variableGuid
+	if authenticated {
+		guid = authenticatedVariableGuid
+	}
+	copy(store[:16], guid[:])
+	binary.LittleEndian.PutUint32(store[16:], uint32(variableStoreHeaderSize+storeSize))
+	store[20] = variableStoreFormatted
+	store[21] = variableStoreHealthy
+	clear(store[22:variableStoreHeaderSize])
+	copy(store[variableStoreHeaderSize:variableStoreHeaderSize+storeSize], bytes.Repeat([]byte{0xff}, storeSize))
+
+	return b
+}
+
+func fixtureVariables(authenticated bool) []*Variable {
+	vars := []*Variable{
+		{
+			Name:       "RamMoreThan3GB",
+			Guid:       testGuid,
+			Attributes: efivario.NonVolatile | efivario.BootServiceAccess,
+			Data:       []byte{1, 0, 0, 0},
+		},
+		{
+			Name:       "Odd",
+			Guid:       testGuid,
+			Attributes: efivario.NonVolatile | efivario.BootServiceAccess | efivario.RuntimeAccess,
+			Data:       []byte{1, 2, 3},
+		},
+	}
+	if authenticated {
+		vars = append(vars, &Variable{
+			Name:           "db",
+			Guid:           ImageSecurityDatabaseGuid,
+			Attributes:     SecureBootAttributes,
+			Data:           []byte("signatures"),
+			MonotonicCount: 7,
+			TimeStamp:      [16]byte{0xe8, 0x07, 1, 2},
+			PubKeyIndex:    3,
+		})
+	}
+	return vars
+}
+
+func TestParseBytesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticated bool
+	}{
+		{"normal", false},
+		{"authenticated", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := newFixture(tt.authenticated, 512)
+
+			img, err := Parse(raw)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if img.Authenticated() != tt.authenticated {
+				t.Fatalf("Authenticated() = %v, want %v", img.Authenticated(), tt.authenticated)
+			}
+			if len(img.Variables) != 0 {
+				t.Fatalf("empty store parsed %d variables", len(img.Variables))
+			}
+
+			want := fixtureVariables(tt.authenticated)
+			for _, v := range want {
+				img.Set(*v)
+			}
+
+			b, err := img.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes: %v", err)
+			}
+			if len(b) != len(raw) {
+				t.Fatalf("Bytes returned %d bytes, want %d", len(b), len(raw))
+			}
+			storeEnd := img.storeOffset + img.storeSize
+			if !bytes.Equal(b[:img.storeOffset], raw[:img.storeOffset]) || !bytes.Equal(b[storeEnd:], raw[storeEnd:]) {
+				t.Fatal("Bytes changed the image outside the variable store")
+			}
+
+			got, err := Parse(b)
+			if err != nil {
+				t.Fatalf("Parse of Bytes: %v", err)
+			}
+			if !reflect.DeepEqual(got.Variables, want) {
+				t.Fatalf("variables = %+v, want %+v", got.Variables, want)
+			}
+		})
+	}
+}
+
+func TestSetDelete(t *testing.T) {
+	img, err := Parse(newFixture(false, 512))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	img.Set(Uint32Variable("Mode", testGuid, efivario.NonVolatile, 1))
+	img.Set(Uint32Variable("Mode", testGuid, efivario.NonVolatile, 2))
+	img.Set(Uint32Variable("Other", testGuid, efivario.NonVolatile, 3))
+
+	b, err := img.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	if img, err = Parse(b); err != nil {
+		t.Fatalf("Parse of Bytes: %v", err)
+	}
+
+	v, ok := img.Get("Mode", testGuid)
+	if !ok {
+		t.Fatal("Mode not found")
+	}
+	if n, _ := v.Uint32(); n != 2 || len(img.Variables) != 2 {
+		t.Fatalf("Mode = %d with %d variables, want 2 with 2", n, len(img.Variables))
+	}
+
+	if !img.Delete("Mode", testGuid) {
+		t.Fatal("Delete of Mode reported false")
+	}
+	if img.Delete("Mode", testGuid) {
+		t.Fatal("second Delete of Mode reported true")
+	}
+	if _, ok := img.Get("Mode", testGuid); ok {
+		t.Fatal("Mode found after Delete")
+	}
+}
+
+func TestParseVariableStates(t *testing.T) {
+	tests := []struct {
+		name  string
+		state byte
+		want  bool
+	}{
+		{"added", varAdded, true},
+		{"added in deletion", varAddedInDeletion, true},
+		{"deleted", varAdded & varInDeletedTransition & 0xfd, false},
+		{"header valid only", 0x7f, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Parse(newFixture(false, 512))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			img.Set(Uint32Variable("Mode", testGuid, efivario.NonVolatile, 1))
+
+			b, err := img.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes: %v", err)
+			}
+			b[img.storeOffset+2] = tt.state
+
+			got, err := Parse(b)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if _, ok := got.Get("Mode", testGuid); ok != tt.want {
+				t.Fatalf("Mode found = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	storeHeader := fixturePadding + fixtureFvHeader
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+		is     error
+	}{
+		{
+			name: "no firmware volume",
+			modify: func(b []byte) []byte {
+				copy(b[fixturePadding+40:], "XXXX")
+				return b
+			},
+			is: ErrNoVariableStore,
+		},
+		{
+			name: "other firmware volume",
+			modify: func(b []byte) []byte {
+				copy(b[fixturePadding+16:], testGuid[:])
+				return b
+			},
+			is: ErrNoVariableStore,
+		},
+		{
+			name: "unknown store format",
+			modify: func(b []byte) []byte {
+				copy(b[storeHeader:], testGuid[:])
+				return b
+			},
+		},
+		{
+			name: "not formatted",
+			modify: func(b []byte) []byte {
+				b[storeHeader+20] = 0xff
+				return b
+			},
+		},
+		{
+			name: "store exceeds image",
+			modify: func(b []byte) []byte {
+				binary.LittleEndian.PutUint32(b[storeHeader+16:], uint32(len(b)))
+				return b
+			},
+		},
+		{
+			name: "truncated store header",
+			modify: func(b []byte) []byte {
+				return b[:storeHeader+variableStoreHeaderSize-1]
+			},
+		},
+		{
+			name: "truncated variable",
+			modify: func(b []byte) []byte {
+				h := b[storeHeader+variableStoreHeaderSize:]
+				binary.LittleEndian.PutUint16(h, variableStartId)
+				h[2] = varAdded
+				binary.LittleEndian.PutUint32(h[8:], 2)
+				binary.LittleEndian.PutUint32(h[12:], 1024)
+				return b
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.modify(newFixture(false, 128)))
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Fatalf("Parse error = %v, want %v", err, tt.is)
+			}
+		})
+	}
+}
+
+func TestBytesStoreFull(t *testing.T) {
+	name := encodeName("Blob")
+
+	tests := []struct {
+		name          string
+		authenticated bool
+		dataSize      int
+		wantErr       bool
+	}{
+		{"normal fits", false, 128 - headerSize - len(name), false},
+		{"normal full", false, 128 - headerSize - len(name) + 1, true},
+		{"authenticated fits", true, 128 - authenticatedHeaderSize - len(name), false},
+		{"authenticated full", true, 128 - authenticatedHeaderSize - len(name) + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Parse(newFixture(tt.authenticated, 128))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			img.Set(Variable{
+				Name:       "Blob",
+				Guid:       testGuid,
+				Attributes: efivario.NonVolatile,
+				Data:       make([]byte, tt.dataSize),
+			})
+
+			_, err = img.Bytes()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bytes error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package firmware
+
+import (
+	"regexp"
+	"time"
+)
+
+var (
+	// uefiVersionRe matches the firmware version string of RPi UEFI builds,
+	// such as "v1.35" or "UEFI Firmware v1.35".
+	uefiVersionRe = regexp.MustCompile(`^(?:UEFI [Ff]irmware )?(v\d+\.\d+[\w.+-]*)$`)
+
+	vcVersionRe = regexp.MustCompile(`VC_BUILD_ID_VERSION: ([0-9a-f]{7,40})`)
+	vcDateRe    = regexp.MustCompile(`VC_BUILD_ID_DATE: ([A-Z][a-z]{2} [ \d]\d \d{4})`)
+)
+
+// printable reports whether c is printable ASCII.
+func printable(c byte) bool {
+	return c >= 0x20 && c < 0x7f
+}
+
+// utf16Strings returns the runs of at least min printable ASCII characters
+// encoded as UTF-16LE in b, the way EDK II stores CHAR16 strings.
+func utf16Strings(b []byte, min int) []string {
+	var out []string
+	for start := 0; start < 2; start++ {
+		var run []byte
+		for i := start; i+1 < len(b); i += 2 {
+			if b[i+1] == 0 && printable(b[i]) {
+				run = append(run, b[i])
+				continue
+			}
+			if len(run) >= min {
+				out = append(out, string(run))
+			}
+			run = run[:0]
+		}
+		if len(run) >= min {
+			out = append(out, string(run))
+		}
+	}
+	return out
+}
+
+// UefiVersion returns the firmware version string of an RPi UEFI image,
+// parsed from the sections of its firmware volumes. A version section names
+// it directly; otherwise the images of uncompressed files are searched for
+// the version PrePi prints in the boot banner. Compressed volumes, such as
+// the DXE volume, are not searched.
+func UefiVersion(b []byte) (string, bool) {
+	var version string
+	visit := func(typ byte, data []byte) bool {
+		var candidates []string
+		switch typ {
+		case sectionVersion:
+			if len(data) > 2 {
+				candidates = []string{decodeName(data[2:])}
+			}
+		case sectionPE32, sectionTE, sectionRaw:
+			candidates = utf16Strings(data, 4)
+		}
+		for _, s := range candidates {
+			if m := uefiVersionRe.FindStringSubmatch(s); m != nil {
+				version = m[1]
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, fv := range firmwareVolumes(b) {
+		if !walkVolume(fv, 0, visit) {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// VideoCoreVersion returns the source revision and build date of a
+// VideoCore firmware such as start4.elf, as reported by vcgencmd version.
+func VideoCoreVersion(b []byte) (version string, built time.Time, ok bool) {
+	m := vcVersionRe.FindSubmatch(b)
+	if m == nil {
+		return "", time.Time{}, false
+	}
+
+	if d := vcDateRe.FindSubmatch(b); d != nil {
+		built, _ = time.Parse("Jan _2 2006", string(d[1]))
+	}
+
+	return string(m[1]), built, true
+}
@@ -0,0 +1,181 @@
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/0x5a17ed/uefi/efi/efiguid"
+)
+
+// lzmaGuid is the GUID defined section EDK II compresses volumes with.
+var lzmaGuid = efiguid.MustFromString("ee4e5898-3914-4259-9d6e-dc7bd79403cf")
+
+// utf16z encodes s as a NUL terminated CHAR16 string.
+func utf16z(s string) []byte {
+	return encodeName(s)
+}
+
+// section returns an EFI_COMMON_SECTION_HEADER section of typ.
+func section(typ byte, data []byte) []byte {
+	size := 4 + len(data)
+	b := []byte{byte(size), byte(size >> 8), byte(size >> 16), typ}
+	return append(b, data...)
+}
+
+// guidedSection returns a GUID defined section holding sections, marked as
+// requiring processing when compressed is set.
+func guidedSection(guid efiguid.GUID, compressed bool, sections ...[]byte) []byte {
+	data := make([]byte, 20)
+	copy(data, guid[:])
+	binary.LittleEndian.PutUint16(data[16:], 4+20)
+	if compressed {
+		binary.LittleEndian.PutUint16(data[18:], guidedProcessingRequired)
+	}
+	return section(sectionGuidDefined, append(data, concatSections(sections...)...))
+}
+
+func concatSections(sections ...[]byte) []byte {
+	var b []byte
+	for _, s := range sections {
+		b = append(b, s...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+// ffsFile returns an FFS file of typ holding sections.
+func ffsFile(typ byte, sections ...[]byte) []byte {
+	body := concatSections(sections...)
+	size := ffsFileHeaderSize + len(body)
+
+	h := make([]byte, ffsFileHeaderSize)
+	copy(h, testGuid[:])
+	h[18] = typ
+	h[20], h[21], h[22] = byte(size), byte(size>>8), byte(size>>16)
+	h[23] = 0xf8
+
+	return append(h, body...)
+}
+
+// volume returns an FFS2 firmware volume with an extended header, the way
+// EDK II lays out FVMAIN_COMPACT, holding files.
+func volume(files ...[]byte) []byte {
+	const headerLength = 0x48
+	const extHeaderSize = 20
+
+	b := make([]byte, headerLength+extHeaderSize)
+	copy(b[16:32], ffs2Guid[:])
+	copy(b[40:44], fvSignature)
+	binary.LittleEndian.PutUint16(b[48:], headerLength)
+	binary.LittleEndian.PutUint16(b[52:], headerLength)
+	binary.LittleEndian.PutUint32(b[headerLength+16:], extHeaderSize)
+
+	for _, f := range files {
+		for len(b)%8 != 0 {
+			b = append(b, 0xff)
+		}
+		b = append(b, f...)
+	}
+	// Free space up to the next block.
+	b = append(b, bytes.Repeat([]byte{0xff}, 0x100-len(b)%0x100)...)
+
+	binary.LittleEndian.PutUint64(b[32:], uint64(len(b)))
+	return b
+}
+
+// teImage returns a TE image body holding the strings of PrePi.
+func teImage(strs ...string) []byte {
+	b := append([]byte("VZ"), make([]byte, 38)...)
+	for _, s := range strs {
+		b = append(b, utf16z(s)...)
+		b = append(b, 0xde, 0xad)
+	}
+	return b
+}
+
+// rpiImage lays out an RPi UEFI image: the Trusted Firmware, whose strings
+// are not part of any volume, followed by volume and the NV variable store.
+func rpiImage(volume []byte) []byte {
+	b := append([]byte{}, utf16z("v9.99")...)
+	b = append(b, make([]byte, 0x400-len(b))...)
+	b = append(b, volume...)
+	return append(b, newFixture(true, 256)...)
+}
+
+func TestUefiVersion(t *testing.T) {
+	const ffsTypeSec, ffsTypeFvImage, ffsTypeDxe = 0x03, 0x0b, 0x07
+	banner := "UEFI firmware (version %s built at %a on %a)\n\r"
+	compressedDxe := ffsFile(ffsTypeFvImage, guidedSection(lzmaGuid, true, section(sectionRaw, utf16z("v0.1"))))
+
+	tests := []struct {
+		name   string
+		image  []byte
+		want   string
+		wantOk bool
+	}{
+		{
+			name: "prepi banner",
+			image: rpiImage(volume(
+				ffsFile(ffsTypePad),
+				ffsFile(ffsTypeSec, section(sectionTE, teImage(banner, "v1.35"))),
+				compressedDxe,
+			)),
+			want:   "v1.35",
+			wantOk: true,
+		},
+		{
+			name: "prefixed version",
+			image: rpiImage(volume(
+				ffsFile(ffsTypeSec, section(sectionPE32, teImage("UEFI Firmware v1.36-rc1"))),
+			)),
+			want:   "v1.36-rc1",
+			wantOk: true,
+		},
+		{
+			name: "version section",
+			image: rpiImage(volume(
+				ffsFile(ffsTypeDxe, section(sectionVersion, append([]byte{1, 0}, utf16z("v1.37")...))),
+			)),
+			want:   "v1.37",
+			wantOk: true,
+		},
+		{
+			name: "unprocessed guided section",
+			image: rpiImage(volume(
+				ffsFile(ffsTypeSec, guidedSection(testGuid, false, section(sectionTE, teImage("v1.38")))),
+			)),
+			want:   "v1.38",
+			wantOk: true,
+		},
+		{
+			name: "nested volume",
+			image: rpiImage(volume(
+				ffsFile(ffsTypeFvImage, section(sectionFvImage, volume(
+					ffsFile(ffsTypeSec, section(sectionTE, teImage("v1.39"))),
+				))),
+			)),
+			want:   "v1.39",
+			wantOk: true,
+		},
+		{
+			name:  "compressed only",
+			image: rpiImage(volume(compressedDxe)),
+		},
+		{
+			name:  "no volume",
+			image: utf16z("v9.99"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := UefiVersion(tt.image)
+			if got != tt.want || ok != tt.wantOk {
+				t.Fatalf("UefiVersion() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
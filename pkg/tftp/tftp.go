@@ -1,6 +1,7 @@
 package tftp
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -19,6 +20,46 @@ import (
 type Handler struct {
 	ctx           context.Context
 	RootDirectory string
+
+	// Netboot reports whether a client is served the netboot chain. Clients
+	// are identified by the MAC address prefixing the requested path, or by
+	// their IP address when there is none. Every client is served when nil.
+	Netboot func(mac net.HardwareAddr, addr netip.Addr) bool
+
+	// Generate returns the content served in place of a file, such as a
+	// script generated per client, and whether there is any. Files are read
+	// from RootDirectory when nil.
+	Generate func(mac net.HardwareAddr, addr netip.Addr, filename string) ([]byte, bool)
+
+	// Served is called after a file has been sent to a client, with the path
+	// of the file relative to RootDirectory.
+	Served func(mac net.HardwareAddr, addr netip.Addr, filename string)
+
+	// Received is called after a file has been written by a client, with the
+	// path of the file relative to RootDirectory.
+	Received func(mac net.HardwareAddr, addr netip.Addr, filename string)
+}
+
+func (h *Handler) served(filename, sent string, rf io.ReaderFrom) {
+	if h.Served == nil {
+		return
+	}
+	mac, addr := client(filename, rf)
+	h.Served(mac, addr, sent)
+}
+
+// client returns the MAC address prefixing filename, if any, and the IP
+// address of the client of transfer.
+func client(filename string, transfer any) (net.HardwareAddr, netip.Addr) {
+	mac, _ := net.ParseMAC(path.Dir(filename))
+
+	var addr netip.Addr
+	if t, ok := transfer.(interface{ RemoteAddr() net.UDPAddr }); ok {
+		remote := t.RemoteAddr()
+		addr, _ = netip.AddrFromSlice(remote.IP)
+	}
+
+	return mac, addr.Unmap()
 }
 
 // ListenAndServe sets up the listener on the given address and serves TFTP requests.
@@ -48,19 +89,44 @@ func (h *Handler) HandleRead(filename string, rf io.ReaderFrom) error {
 	}
 	defer root.Close()
 
+	if h.Netboot != nil {
+		mac, addr := client(filename, rf)
+		if !h.Netboot(mac, addr) {
+			// Refusing the transfer makes the Pi fall back to its next boot
+			// mode.
+			return fmt.Errorf("netboot of %s refused for %s", filename, addr)
+		}
+	}
+
+	if h.Generate != nil {
+		mac, addr := client(filename, rf)
+		if b, ok := h.Generate(mac, addr, filename); ok {
+			n, err := rf.ReadFrom(bytes.NewReader(b))
+			if err != nil {
+				fmt.Printf("sending generated %s: %v\n", filename, err)
+				return nil
+			}
+			fmt.Printf("%d bytes sent\n", n)
+			h.served(filename, filename, rf)
+			return nil
+		}
+	}
+
 	if _, err := root.Stat(filename); err == nil {
 		// file exists
 		file, err := root.Open(filename)
 		if err != nil {
-			fmt.Printf("opening %s: %w", filename, err)
+			fmt.Printf("opening %s: %v\n", filename, err)
 			return nil
 		}
+		defer file.Close()
 		n, err := rf.ReadFrom(file)
 		if err != nil {
-			fmt.Printf("reading %s: %w", filename, err)
+			fmt.Printf("reading %s: %v\n", filename, err)
 			return nil
 		}
 		fmt.Printf("%d bytes sent\n", n)
+		h.served(filename, filename, rf)
 		return nil
 
 	} else if _, err := net.ParseMAC(path.Dir(filename)); err == nil {
@@ -75,15 +141,17 @@ func (h *Handler) HandleRead(filename string, rf io.ReaderFrom) error {
 		if _, err := root.Stat(newFp); err == nil {
 			file, err := root.Open(newFp)
 			if err != nil {
-				fmt.Printf("opening %s: %w", newFp, err)
+				fmt.Printf("opening %s: %v\n", newFp, err)
 				return nil
 			}
+			defer file.Close()
 			n, err := rf.ReadFrom(file)
 			if err != nil {
-				fmt.Printf("reading %s: %w", newFp, err)
+				fmt.Printf("reading %s: %v\n", newFp, err)
 				return nil
 			}
 			fmt.Printf("%d bytes sent\n", n)
+			h.served(filename, newFp, rf)
 			return nil
 		} else {
 			fmt.Printf("file not found: %v\n", newFp)
@@ -91,7 +159,6 @@ func (h *Handler) HandleRead(filename string, rf io.ReaderFrom) error {
 		}
 	} else {
 		fmt.Printf("error checking if file exists: %v\n", err)
-		return nil
 	}
 
 	// content, ok := binary.Files[filepath.Base(shortfile)]
@@ -142,6 +209,11 @@ func (h *Handler) HandleWrite(filename string, wt io.WriterTo) error {
 		return nil
 	}
 	fmt.Printf("%d bytes received\n", n)
+
+	if h.Received != nil {
+		mac, addr := client(filename, wt)
+		h.Received(mac, addr, filename)
+	}
 	return nil
 
 	// err := fmt.Errorf("access_violation: %w", os.ErrPermission)